@@ -0,0 +1,70 @@
+package configurator
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	encodingHex       = "hex"
+	encodingBase64    = "base64"
+	encodingBase64URL = "base64url"
+	encodingRaw       = "raw"
+)
+
+// decodeBytes decodes v with the `encoding` of opts, standard base64 by
+// default, and checks the decoded length against the `len` of opts if any.
+// The base64 encodings accept unpadded values, and hex accepts a `0x` prefix.
+func decodeBytes(v string, opts valueOptions) ([]byte, error) {
+	var b []byte
+	var err error
+	switch opts.encoding {
+	case "", encodingBase64:
+		b, err = decodeBase64(v, base64.StdEncoding)
+	case encodingBase64URL:
+		b, err = decodeBase64(v, base64.URLEncoding)
+	case encodingHex:
+		b, err = hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(v, "0x"), "0X"))
+	case encodingRaw:
+		b = []byte(v)
+	default:
+		return nil, fmt.Errorf("decodeBytes: %w encoding [%s]", ErrUnsupported, opts.encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decodeBytes: invalid %s: %w", encodingName(opts.encoding), err)
+	}
+	if opts.hasLen && len(b) != opts.length {
+		return nil, fmt.Errorf("%w, got %d bytes, want %d", ErrInvalidLength, len(b), opts.length)
+	}
+	return b, nil
+}
+
+func decodeBase64(v string, enc *base64.Encoding) ([]byte, error) {
+	if strings.HasSuffix(v, "=") {
+		return enc.DecodeString(v)
+	}
+	return enc.WithPadding(base64.NoPadding).DecodeString(v)
+}
+
+// encodeBytes encodes b with the `encoding` of opts.
+func encodeBytes(b []byte, opts valueOptions) string {
+	switch opts.encoding {
+	case encodingBase64URL:
+		return base64.URLEncoding.EncodeToString(b)
+	case encodingHex:
+		return hex.EncodeToString(b)
+	case encodingRaw:
+		return string(b)
+	default:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+func encodingName(encoding string) string {
+	if encoding == "" {
+		return encodingBase64
+	}
+	return encoding
+}
@@ -0,0 +1,104 @@
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeBytes(t *testing.T) {
+	tests := []struct {
+		in     string
+		opts   valueOptions
+		expect []byte
+	}{
+		{in: "AQIDBAoL", expect: []byte{1, 2, 3, 4, 10, 11}},
+		{in: "AQID", opts: valueOptions{encoding: encodingBase64}, expect: []byte{1, 2, 3}},
+		{in: "-_8=", opts: valueOptions{encoding: encodingBase64URL}, expect: []byte{0xfb, 0xff}},
+		{in: "-_8", opts: valueOptions{encoding: encodingBase64URL}, expect: []byte{0xfb, 0xff}},
+		{in: "0a0B", opts: valueOptions{encoding: encodingHex}, expect: []byte{0x0a, 0x0b}},
+		{in: "0xff00", opts: valueOptions{encoding: encodingHex, length: 2, hasLen: true}, expect: []byte{0xff, 0x00}},
+		{in: "salt", opts: valueOptions{encoding: encodingRaw}, expect: []byte("salt")},
+	}
+	for _, tt := range tests {
+		b, err := decodeBytes(tt.in, tt.opts)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.expect, b, tt.in)
+		if tt.opts.encoding != encodingBase64URL {
+			continue
+		}
+		assert.Equal(t, "-_8=", encodeBytes(b, tt.opts))
+	}
+
+	_, err := decodeBytes("zz", valueOptions{encoding: encodingHex})
+	assert.Error(t, err)
+	_, err = decodeBytes("0a0b", valueOptions{encoding: encodingHex, length: 32, hasLen: true})
+	assert.True(t, errors.Is(err, ErrInvalidLength))
+}
+
+type bytesExample struct {
+	Key  []byte `json:"key" yaml:"key" config:"env,flag,encoding=hex,len=4"`
+	Salt []byte `json:"salt" yaml:"salt" config:"env,flag,encoding=base64url"`
+	Raw  []byte `json:"raw" yaml:"raw" config:"env,flag,encoding=raw,default=pepper"`
+}
+
+func TestBytesProviders(t *testing.T) {
+	expect := &bytesExample{
+		Key:  []byte{0xde, 0xad, 0xbe, 0xef},
+		Salt: []byte{0xfb, 0xff},
+		Raw:  []byte("pepper"),
+	}
+
+	t.Run("env and default", func(t *testing.T) {
+		os.Setenv("APP_KEY", "deadbeef")
+		os.Setenv("APP_SALT", "-_8")
+		defer os.Unsetenv("APP_KEY")
+		defer os.Unsetenv("APP_SALT")
+
+		cfg := &bytesExample{}
+		err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider()).Load(cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, expect, cfg)
+
+		os.Setenv("APP_KEY", "deadbeef00")
+		err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(&bytesExample{})
+		assert.True(t, errors.Is(err, ErrInvalidLength))
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		os.Args = []string{"cmd", "-key=DEADBEEF", "-salt=-_8=", "-raw=pepper"}
+		resetForTesting()
+
+		cfg := &bytesExample{}
+		err := NewConfigurator(WithFileProvider(""), WithFlagProvider()).Load(cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, expect, cfg)
+	})
+
+	files := map[string]string{
+		"*.json": `{"key":"deadbeef","salt":"-_8","raw":"pepper"}`,
+		"*.yaml": "key: deadbeef\nsalt: -_8\nraw: pepper\n",
+	}
+	for pattern, content := range files {
+		pattern, content := pattern, content
+		t.Run(pattern, func(t *testing.T) {
+			f, err := ioutil.TempFile("", pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, err = f.WriteString(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &bytesExample{}
+			err = NewConfigurator(WithFileProvider(f.Name())).Load(cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, expect, cfg)
+		})
+	}
+}
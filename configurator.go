@@ -1,7 +1,11 @@
 package configurator
 
 import (
+	"errors"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 )
 
 type ConfiguratorOptions struct {
@@ -11,6 +15,10 @@ type ConfiguratorOptions struct {
 	envPrefix     string
 	enableFlag    bool
 	enableDefault bool
+	lenientBool   bool
+	strictFile    bool
+	watchInterval time.Duration
+	watchErrors   func(error)
 }
 
 type ConfiguratorOption func(*ConfiguratorOptions)
@@ -22,6 +30,30 @@ func WithFileProvider(filename string) ConfiguratorOption {
 	}
 }
 
+// WithStrictFile makes the file provider report every key of the file
+// matching no field of the config, with its line and the closest field name.
+func WithStrictFile() ConfiguratorOption {
+	return func(co *ConfiguratorOptions) {
+		co.strictFile = true
+	}
+}
+
+// WithWatchInterval sets how often Watch checks the file for changes, every
+// second by default.
+func WithWatchInterval(interval time.Duration) ConfiguratorOption {
+	return func(co *ConfiguratorOptions) {
+		co.watchInterval = interval
+	}
+}
+
+// WithWatchErrorHandler sets the function called with the error of every
+// reload of Watch failing, which Watch skips otherwise.
+func WithWatchErrorHandler(fn func(error)) ConfiguratorOption {
+	return func(co *ConfiguratorOptions) {
+		co.watchErrors = fn
+	}
+}
+
 func WithENVProvider(prefix string) ConfiguratorOption {
 	return func(co *ConfiguratorOptions) {
 		co.enableENV = true
@@ -35,16 +67,36 @@ func WithFlagProvider() ConfiguratorOption {
 	}
 }
 
+// WithLenientBool makes every provider accept yes/no, on/off, y/n and
+// enable(d)/disable(d) for bool fields, besides the values of strconv.ParseBool.
+func WithLenientBool() ConfiguratorOption {
+	return func(co *ConfiguratorOptions) {
+		co.lenientBool = true
+	}
+}
+
 func WithDefaultProvider() ConfiguratorOption {
 	return func(co *ConfiguratorOptions) {
 		co.enableDefault = true
 	}
 }
 
+// Provider sets the fields of a config from a source. A Provider reports the
+// values it could not set with a *LoadError, so that Load goes on with the
+// other providers; any other error stops Load.
 type Provider interface {
 	Provide(interface{}, StructInfo) error
 }
 
+// The sources of the values, see FieldError.
+const (
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceArg     = "arg"
+	SourceDefault = "default"
+)
+
 func NewConfigurator(options ...ConfiguratorOption) *Configurator {
 	opts := &ConfiguratorOptions{
 		enableFile:    true,
@@ -53,6 +105,7 @@ func NewConfigurator(options ...ConfiguratorOption) *Configurator {
 		envPrefix:     "",
 		enableFlag:    false,
 		enableDefault: false,
+		watchInterval: time.Second,
 	}
 	for _, fn := range options {
 		fn(opts)
@@ -60,7 +113,9 @@ func NewConfigurator(options ...ConfiguratorOption) *Configurator {
 
 	providers := make([]Provider, 0, 4)
 	if opts.enableFile && strings.TrimSpace(opts.filename) != "" {
-		providers = append(providers, NewFileProvider(opts.filename))
+		fp := NewFileProvider(opts.filename)
+		fp.strict = opts.strictFile
+		providers = append(providers, fp)
 	}
 	if opts.enableENV {
 		providers = append(providers, NewENVProvider(opts.envPrefix))
@@ -72,24 +127,112 @@ func NewConfigurator(options ...ConfiguratorOption) *Configurator {
 		providers = append(providers, NewDefaultProvider())
 	}
 
-	return &Configurator{
-		providers: providers,
+	c := &Configurator{
+		providers:     providers,
+		conv:          &converters{},
+		watchInterval: opts.watchInterval,
+		watchErrors:   opts.watchErrors,
 	}
+	if opts.lenientBool {
+		c.RegisterConverter(reflect.TypeOf(false), func(s string) (interface{}, error) {
+			return parseLenientBool(s)
+		})
+	}
+	return c
 }
 
 type Configurator struct {
 	providers []Provider
+	conv      *converters
+
+	watchInterval time.Duration
+	watchErrors   func(error)
+
+	// loadMu serializes the runs of the providers, which keep state between
+	// them, like the command line parsed by the flag provider.
+	loadMu sync.Mutex
+
+	mu      sync.RWMutex
+	subs    []subscription
+	origins *origins
+	// loaded is the config of the last Load, see Dump.
+	loaded interface{}
 }
 
+// RegisterConverter registers a converter for typ used by this Configurator
+// only, taking precedence over the global converters.
+func (c *Configurator) RegisterConverter(typ reflect.Type, fn Converter) {
+	c.conv.register(typ, fn)
+}
+
+// Load sets the fields of the struct v points to from the providers, in
+// order, then validates it. The providers set a copy of v, copied into v only
+// if it loads and validates, so that v is left as it is if Load fails.
 func (c *Configurator) Load(v interface{}) error {
-	si, err := getStructInfo(v, nil)
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+	scratch := reflect.New(rv.Elem().Type())
+	scratch.Elem().Set(deepCopy(rv.Elem(), c.conv))
+
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	o := newOrigins()
+	si, err := walkStruct(scratch.Interface(), nil, c.conv, o)
 	if err != nil {
 		return err
 	}
+	if err := c.provide(scratch.Interface(), si); err != nil {
+		return err
+	}
+	rv.Elem().Set(scratch.Elem())
+	c.setLoaded(v, o)
+	return nil
+}
+
+// provide runs the providers on v, then validates it.
+func (c *Configurator) provide(v interface{}, si StructInfo) error {
+	var errs fieldErrors
 	for _, p := range c.providers {
-		if err := p.Provide(v, si); err != nil {
+		err := p.Provide(v, si)
+		var le *LoadError
+		switch {
+		case err == nil:
+		case errors.As(err, &le):
+			errs = append(errs, le.Errors...)
+		default:
 			return err
 		}
 	}
-	return nil
+	if err := errs.err(); err != nil {
+		return err
+	}
+	return validate(v, si, c.conv)
+}
+
+// setLoaded records v and the origins of its values as the last config
+// loaded.
+func (c *Configurator) setLoaded(v interface{}, o *origins) {
+	c.mu.Lock()
+	c.origins, c.loaded = o, v
+	c.mu.Unlock()
+}
+
+// Origins returns where the values of the last Load come from, by the paths
+// of the fields, see FieldInfo.Path. A path set by several providers has the
+// origin of the value kept.
+func (c *Configurator) Origins() map[string]Origin {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.origins.last()
+}
+
+// Explain describes where the value of the field at path comes from in the
+// last Load, including the values it overrides, e.g.
+// `database.host: env APP_DATABASE_HOST="db", overriding file config.yaml:2:9="localhost"`.
+func (c *Configurator) Explain(path string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.origins.explain(path)
 }
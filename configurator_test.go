@@ -0,0 +1,100 @@
+package configurator
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad_Transactional(t *testing.T) {
+	type database struct {
+		Host string `config:"env"`
+		Port int    `config:"env,min=1"`
+	}
+	type example struct {
+		Name     string `config:"env"`
+		Database *database
+		Labels   map[string]string `config:"env"`
+		Tags     []string          `config:"env"`
+	}
+
+	os.Setenv("APP_NAME", "svc")
+	os.Setenv("APP_DATABASE_HOST", "db")
+	os.Setenv("APP_LABELS", "a=1")
+	os.Setenv("APP_TAGS", "x,y")
+	os.Setenv("APP_DATABASE_PORT", "0")
+	defer os.Unsetenv("APP_NAME")
+	defer os.Unsetenv("APP_DATABASE_HOST")
+	defer os.Unsetenv("APP_LABELS")
+	defer os.Unsetenv("APP_TAGS")
+	defer os.Unsetenv("APP_DATABASE_PORT")
+
+	labels := map[string]string{"b": "2"}
+	tags := []string{"z"}
+	cfg := &example{Name: "old", Labels: labels, Tags: tags}
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"))
+	err := c.Load(cfg)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	assert.Equal(t, &example{Name: "old", Labels: map[string]string{"b": "2"}, Tags: []string{"z"}}, cfg)
+	assert.Nil(t, cfg.Database, "the walk of the fields allocates a copy")
+	assert.Empty(t, c.Origins())
+
+	os.Setenv("APP_DATABASE_PORT", "5432")
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, &example{
+		Name:     "svc",
+		Database: &database{Host: "db", Port: 5432},
+		Labels:   map[string]string{"a": "1"},
+		Tags:     []string{"x", "y"},
+	}, cfg)
+	assert.Equal(t, map[string]string{"b": "2"}, labels)
+	assert.Equal(t, []string{"z"}, tags)
+
+	assert.True(t, errors.Is(c.Load(example{}), ErrInvalidConfig))
+	assert.True(t, errors.Is(c.Load((*example)(nil)), ErrInvalidConfig))
+}
+
+func TestDeepCopy(t *testing.T) {
+	type node struct {
+		Name     string
+		Next     *node
+		Children []*node
+		Attrs    map[string][]int
+		Any      interface{}
+	}
+	a := &node{Name: "a", Attrs: map[string][]int{"x": {1}}, Any: []string{"s"}}
+	a.Next = a
+	a.Children = []*node{{Name: "b"}}
+
+	c := deepCopy(reflect.ValueOf(a), nil).Interface().(*node)
+	assert.Equal(t, "a", c.Name)
+	assert.True(t, c != a)
+	assert.True(t, c.Next == c, "the cycles are kept")
+	assert.True(t, c.Children[0] != a.Children[0])
+	c.Attrs["x"][0] = 2
+	c.Any.([]string)[0] = "t"
+	assert.Equal(t, []int{1}, a.Attrs["x"])
+	assert.Equal(t, []string{"s"}, a.Any)
+}
+
+func TestDeepCopy_Opaque(t *testing.T) {
+	type example struct {
+		TLS   *tls.Config
+		Since *time.Time
+		IPs   []net.IP
+	}
+	since := time.Now()
+	a := &example{TLS: &tls.Config{ServerName: "a"}, Since: &since, IPs: []net.IP{net.ParseIP("::1")}}
+
+	c := deepCopy(reflect.ValueOf(a), nil).Interface().(*example)
+	assert.True(t, c.TLS == a.TLS, "the structs with unexported fields are shared")
+	assert.True(t, c.Since != a.Since)
+	assert.Equal(t, since, *c.Since)
+	assert.Equal(t, a.IPs, c.IPs)
+}
@@ -0,0 +1,120 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Converter converts a raw string from any provider into a value of the type
+// it is registered for.
+type Converter func(string) (interface{}, error)
+
+type converters struct {
+	mu sync.RWMutex
+	m  map[reflect.Type]Converter
+}
+
+var globalConverters = &converters{}
+
+// RegisterConverter registers a converter for typ used by every Configurator.
+// A converter registered on a Configurator takes precedence.
+func RegisterConverter(typ reflect.Type, fn Converter) {
+	globalConverters.register(typ, fn)
+}
+
+func (c *converters) register(typ reflect.Type, fn Converter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[reflect.Type]Converter)
+	}
+	c.m[typ] = fn
+}
+
+func (c *converters) lookup(typ reflect.Type) (Converter, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn, ok := c.m[typ]
+	return fn, ok
+}
+
+// findConverter returns the converter for typ, or for the type typ points to
+// or is pointed to by, looking at local before the global converters.
+func findConverter(local *converters, typ reflect.Type) (Converter, bool) {
+	candidates := []reflect.Type{typ}
+	if typ.Kind() == reflect.Ptr {
+		candidates = append(candidates, typ.Elem())
+	} else {
+		candidates = append(candidates, reflect.PtrTo(typ))
+	}
+	for _, c := range []*converters{local, globalConverters} {
+		for _, t := range candidates {
+			if fn, ok := c.lookup(t); ok {
+				return fn, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// convertersOf returns the converters of the Configurator walking si, if any.
+func convertersOf(si StructInfo) *converters {
+	if si == nil {
+		return nil
+	}
+	for _, fi := range si.Fields() {
+		if f, ok := fi.(*fieldInfo); ok {
+			return f.conv
+		}
+	}
+	return nil
+}
+
+// setConvertedValue sets val to the value converted from v by fn. The result
+// may be of typ, of the type typ points to, of a pointer to typ, or
+// convertible to typ.
+func setConvertedValue(val reflect.Value, typ reflect.Type, v string, fn Converter) error {
+	i, err := fn(v)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(i)
+	switch {
+	case !rv.IsValid():
+		val.Set(reflect.Zero(typ))
+	case rv.Type() == typ:
+		val.Set(rv)
+	case typ.Kind() == reflect.Ptr && rv.Type() == typ.Elem():
+		p := reflect.New(typ.Elem())
+		p.Elem().Set(rv)
+		val.Set(p)
+	case rv.Kind() == reflect.Ptr && rv.Type().Elem() == typ:
+		if rv.IsNil() {
+			val.Set(reflect.Zero(typ))
+			return nil
+		}
+		val.Set(rv.Elem())
+	case rv.Type().ConvertibleTo(typ):
+		val.Set(rv.Convert(typ))
+	default:
+		return fmt.Errorf("setConvertedValue: %w, converter returned [%s] for type [%s]", ErrUnsupported, rv.Type().String(), typ.String())
+	}
+	return nil
+}
+
+// parseLenientBool parses the bool values people commonly type into env
+// variables and values files, case-insensitively.
+func parseLenientBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on", "enable", "enabled":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off", "disable", "disabled":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w [%s], allowed values are [true, false, yes, no, on, off, enabled, disabled]", ErrNotAllowed, s)
+}
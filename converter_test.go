@@ -0,0 +1,151 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testVersion struct {
+	Major, Minor int
+}
+
+func TestConverter(t *testing.T) {
+	RegisterConverter(reflect.TypeOf(&url.URL{}), func(s string) (interface{}, error) {
+		return url.Parse(s)
+	})
+	RegisterConverter(reflect.TypeOf(big.Int{}), func(s string) (interface{}, error) {
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		return i, nil
+	})
+
+	type example struct {
+		Endpoint *url.URL            `config:"env"`
+		Mirrors  []*url.URL          `config:"env"`
+		Total    big.Int             `config:"env"`
+		Pattern  *regexp.Regexp      `config:"env"`
+		Version  testVersion         `config:"flag"`
+		ByName   map[string]*url.URL `config:"env"`
+	}
+
+	envs := map[string]string{
+		"APP_ENDPOINT": "https://example.com/api",
+		"APP_MIRRORS":  "https://a.example.com,https://b.example.com",
+		"APP_TOTAL":    "123456789012345678901234567890",
+		"APP_PATTERN":  "^a+$",
+		"APP_BYNAME":   "x=https://x.example.com",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	os.Args = []string{"cmd", "-version=1.2"}
+	resetForTesting()
+
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithFlagProvider())
+	c.RegisterConverter(reflect.TypeOf(&regexp.Regexp{}), func(s string) (interface{}, error) {
+		return regexp.Compile(s)
+	})
+	c.RegisterConverter(reflect.TypeOf(testVersion{}), func(s string) (interface{}, error) {
+		var v testVersion
+		_, err := fmt.Sscanf(s, "%d.%d", &v.Major, &v.Minor)
+		return v, err
+	})
+
+	cfg := &example{}
+	err := c.Load(cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "https://example.com/api", cfg.Endpoint.String())
+	assert.Len(t, cfg.Mirrors, 2)
+	assert.Equal(t, "https://b.example.com", cfg.Mirrors[1].String())
+	assert.Equal(t, "123456789012345678901234567890", cfg.Total.String())
+	assert.True(t, cfg.Pattern.MatchString("aaa"))
+	assert.Equal(t, testVersion{Major: 1, Minor: 2}, cfg.Version)
+	assert.Equal(t, "https://x.example.com", cfg.ByName["x"].String())
+
+	_, ok := findConverter(nil, reflect.TypeOf(testVersion{}))
+	assert.False(t, ok, "converters of a Configurator must not leak")
+}
+
+func TestConverter_File(t *testing.T) {
+	type backend struct {
+		URL *url.URL `yaml:"url" json:"url"`
+	}
+	type example struct {
+		Endpoint *url.URL           `yaml:"endpoint" json:"endpoint"`
+		Version  testVersion        `yaml:"version" json:"version"`
+		Enabled  bool               `yaml:"enabled" json:"enabled"`
+		Backends map[string]backend `yaml:"backends" json:"backends"`
+		Unset    *url.URL           `yaml:"unset" json:"unset"`
+	}
+	files := map[string]string{
+		".yaml": "endpoint: http://a/b\nversion: 1.2\nenabled: yes\nbackends:\n  x:\n    url: http://x\nunset: null\n",
+		".json": `{"endpoint": "http://a/b", "version": "1.2", "enabled": "yes", "backends": {"x": {"url": "http://x"}}, "unset": null}`,
+	}
+
+	for ext, content := range files {
+		t.Run(ext, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "*"+ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(content); err != nil {
+				t.Fatal(err)
+			}
+
+			c := NewConfigurator(WithFileProvider(f.Name()), WithLenientBool())
+			c.RegisterConverter(reflect.TypeOf(&url.URL{}), func(s string) (interface{}, error) {
+				return url.Parse(s)
+			})
+			c.RegisterConverter(reflect.TypeOf(testVersion{}), func(s string) (interface{}, error) {
+				var v testVersion
+				_, err := fmt.Sscanf(s, "%d.%d", &v.Major, &v.Minor)
+				return v, err
+			})
+			cfg := &example{}
+			assert.NoError(t, c.Load(cfg))
+			assert.Equal(t, &example{
+				Endpoint: &url.URL{Scheme: "http", Host: "a", Path: "/b"},
+				Version:  testVersion{Major: 1, Minor: 2},
+				Enabled:  true,
+				Backends: map[string]backend{"x": {URL: &url.URL{Scheme: "http", Host: "x"}}},
+			}, cfg)
+			assert.Contains(t, c.Explain("version"), `="1.2"`)
+		})
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("version: one\n"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfigurator(WithFileProvider(f.Name()))
+	c.RegisterConverter(reflect.TypeOf(testVersion{}), func(s string) (interface{}, error) {
+		var v testVersion
+		_, err := fmt.Sscanf(s, "%d.%d", &v.Major, &v.Minor)
+		return v, err
+	})
+	err = c.Load(&example{})
+	var le *LoadError
+	if assert.True(t, errors.As(err, &le)) {
+		assert.Equal(t, "version", le.Errors[0].Path)
+		assert.Equal(t, f.Name()+":1:10", le.Errors[0].Key)
+		assert.Equal(t, "one", le.Errors[0].RawValue)
+	}
+}
@@ -6,6 +6,38 @@ func NewDefaultProvider() *defaultProvider {
 	return &defaultProvider{}
 }
 
+// Provide sets the default value of every field that no previous provider set
+// and that is still zero, so that a value set on purpose to false or 0 is
+// kept.
 func (p defaultProvider) Provide(v interface{}, si StructInfo) error {
-	return nil
+	var errs fieldErrors
+	p.provide(si.Fields(), &errs)
+	return errs.err()
+}
+
+func (p defaultProvider) provide(fields []FieldInfo, errs *fieldErrors) {
+	for _, fi := range fields {
+		if fi.Elem() != nil {
+			for _, key := range fi.Keys() {
+				err := fi.Element(key, func(si StructInfo) error {
+					p.provide(si.Fields(), errs)
+					return nil
+				})
+				if err != nil {
+					errs.add(fi, SourceDefault, "", key, err)
+				}
+			}
+			continue
+		}
+
+		d := fi.DefVal()
+		if d == "" || fi.isSet() || !fi.Value().IsZero() {
+			continue
+		}
+		if err := setFieldValue(fi.Value(), fi.StructField().Type, d, fi.options()); err != nil {
+			errs.add(fi, SourceDefault, "", d, err)
+			continue
+		}
+		fi.setOrigin(SourceDefault, "", d)
+	}
 }
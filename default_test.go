@@ -0,0 +1,95 @@
+package configurator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultProvider(t *testing.T) {
+	type example struct {
+		Name  string   `config:"default=foo"`
+		Set   string   `config:"default=foo"`
+		Port  int      `config:"default=8080"`
+		Hosts []string `config:"default=a;b,sep=;"`
+		None  string
+	}
+
+	cfg := &example{Set: "bar"}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewDefaultProvider().Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Name:  "foo",
+		Set:   "bar",
+		Port:  8080,
+		Hosts: []string{"a", "b"},
+	}, cfg)
+}
+
+func TestDefaultProvider_Elements(t *testing.T) {
+	type listener struct {
+		Host string `config:"default=localhost"`
+		Port int    `config:"default=80"`
+	}
+	type example struct {
+		Listeners []listener
+		Named     map[string]listener
+	}
+
+	cfg := &example{
+		Listeners: []listener{{Port: 8080}, {Host: "a"}},
+		Named:     map[string]listener{"x": {}},
+	}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewDefaultProvider().Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Listeners: []listener{{Host: "localhost", Port: 8080}, {Host: "a", Port: 80}},
+		Named:     map[string]listener{"x": {Host: "localhost", Port: 80}},
+	}, cfg)
+}
+
+func TestDefaultProvider_ZeroValues(t *testing.T) {
+	type example struct {
+		Enabled bool `yaml:"enabled" config:"env,default=true"`
+		Retries int  `yaml:"retries" config:"env,default=3"`
+		Debug   bool `yaml:"debug" config:"default=true"`
+		Workers int  `yaml:"workers" config:"default=4"`
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("debug: false\nworkers: 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_ENABLED", "false")
+	os.Setenv("APP_RETRIES", "0")
+	defer os.Unsetenv("APP_ENABLED")
+	defer os.Unsetenv("APP_RETRIES")
+
+	c := NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP"), WithDefaultProvider())
+	cfg := &example{}
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, &example{}, cfg)
+	assert.Equal(t, `enabled: env APP_ENABLED="false"`, c.Explain("enabled"))
+	assert.Equal(t, fmt.Sprintf(`workers: file %s:2:10="0"`, f.Name()), c.Explain("workers"))
+
+	os.Unsetenv("APP_ENABLED")
+	os.Unsetenv("APP_RETRIES")
+	c = NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider())
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, &example{Enabled: true, Retries: 3, Debug: true, Workers: 4}, cfg)
+}
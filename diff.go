@@ -0,0 +1,170 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// ChangeAdded is a value only in the new config, like a new element of a
+	// collection.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a value only in the old config.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified is a value differing between the configs.
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a value differing between two configs, see Diff. The values are
+// rendered like Dump does, the secrets masked.
+type Change struct {
+	// Path is the path of the field, see FieldInfo.Path.
+	Path string
+	Kind ChangeKind
+	// Old is the old value, nil if added.
+	Old interface{}
+	// New is the new value, nil if removed.
+	New interface{}
+}
+
+// String formats the change like `log.level: info -> debug`.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %s", c.Path, formatValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %s", c.Path, formatValue(c.Old))
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatValue(c.Old), formatValue(c.New))
+}
+
+// Diff returns the values differing between the configs old and new, which
+// point to structs of the same type, in the order of the fields, the removed
+// values first. A nil pointer is an empty config. Diff returns nil if old and
+// new are not pointers to structs of the same type.
+func Diff(old, new interface{}) []Change {
+	return diff(old, new, nil)
+}
+
+// diff is Diff, telling the leaf types with the converters conv, like the
+// converters registered on a Configurator.
+func diff(old, new interface{}, conv *converters) []Change {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	if ov.Kind() != reflect.Ptr || ov.Type() != nv.Type() || ov.Type().Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	oldLeaves, _, err := diffLeaves(ov, conv)
+	if err != nil {
+		return nil
+	}
+	newLeaves, _, err := diffLeaves(nv, conv)
+	if err != nil {
+		return nil
+	}
+
+	byPath := make(map[string]diffLeaf, len(newLeaves))
+	for _, l := range newLeaves {
+		byPath[l.path] = l
+	}
+	var changes []Change
+	seen := make(map[string]bool, len(oldLeaves))
+	for _, o := range oldLeaves {
+		seen[o.path] = true
+		n, ok := byPath[o.path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: o.path, Kind: ChangeRemoved, Old: o.render()})
+		case !o.equal(n):
+			changes = append(changes, Change{Path: o.path, Kind: ChangeModified, Old: o.render(), New: n.render()})
+		}
+	}
+	for _, n := range newLeaves {
+		if !seen[n.path] {
+			changes = append(changes, Change{Path: n.path, Kind: ChangeAdded, New: n.render()})
+		}
+	}
+	return changes
+}
+
+// diffLeaf is a value compared by Diff.
+type diffLeaf struct {
+	path string
+	// value is invalid for a nil element of a collection.
+	value  reflect.Value
+	opts   valueOptions
+	secret bool
+}
+
+func (l diffLeaf) equal(o diffLeaf) bool {
+	if !l.value.IsValid() || !o.value.IsValid() {
+		return l.value.IsValid() == o.value.IsValid()
+	}
+	return reflect.DeepEqual(l.value.Interface(), o.value.Interface())
+}
+
+func (l diffLeaf) render() interface{} {
+	switch {
+	case !l.value.IsValid():
+		return nil
+	case l.secret && !l.value.IsZero():
+		return secretMask
+	}
+	return dumpValue(l.value, l.opts)
+}
+
+// diffLeaves returns the values of the config v points to, along with the
+// values of the structs and the collections holding them by path, walking a
+// copy of it as the walk of the fields allocates the nil pointers.
+func diffLeaves(v reflect.Value, conv *converters) ([]diffLeaf, map[string]reflect.Value, error) {
+	c := reflect.New(v.Type().Elem())
+	if !v.IsNil() {
+		c.Elem().Set(deepCopy(v.Elem(), conv))
+	}
+	si, err := walkStruct(c.Interface(), nil, conv, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var leaves []diffLeaf
+	nodes := make(map[string]reflect.Value)
+	err = addDiffLeaves(&leaves, nodes, si.Fields())
+	return leaves, nodes, err
+}
+
+// addDiffLeaves appends the values of the fields, and of the elements of
+// their collections, to leaves, and records them and the values of their
+// lineages into nodes.
+func addDiffLeaves(leaves *[]diffLeaf, nodes map[string]reflect.Value, fields []FieldInfo) error {
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		for _, p := range f.lineage() {
+			if _, ok := nodes[p.Path()]; !ok {
+				nodes[p.Path()] = p.val
+			}
+		}
+		if f.elem == nil {
+			*leaves = append(*leaves, diffLeaf{path: f.Path(), value: f.val, opts: f.options(), secret: f.isSecret()})
+			continue
+		}
+		for _, key := range f.Keys() {
+			if nilElement(f.val, key) {
+				path := f.Path() + "[" + key + "]"
+				*leaves = append(*leaves, diffLeaf{path: path})
+				nodes[path] = reflect.Value{}
+				continue
+			}
+			err := f.Element(key, func(si StructInfo) error {
+				return addDiffLeaves(leaves, nodes, si.Fields())
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
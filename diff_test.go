@@ -0,0 +1,85 @@
+package configurator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	type pool struct {
+		Size int
+	}
+	type database struct {
+		Host     string
+		Password string `config:"secret"`
+		Pool     *pool
+	}
+	type listener struct {
+		Port int
+	}
+	type example struct {
+		Level     string
+		Timeout   time.Duration
+		Tags      []string
+		Token     Secret
+		Database  database
+		Listeners []listener
+		Backends  map[string]*pool
+	}
+
+	old := &example{
+		Level:     "info",
+		Timeout:   time.Second,
+		Tags:      []string{"a"},
+		Token:     "t1",
+		Database:  database{Host: "db", Password: "p1"},
+		Listeners: []listener{{Port: 80}, {Port: 443}},
+		Backends:  map[string]*pool{"a": {Size: 1}, "b": nil},
+	}
+	new := &example{
+		Level:     "debug",
+		Timeout:   time.Second,
+		Tags:      []string{"a", "b"},
+		Token:     "t2",
+		Database:  database{Host: "db", Password: "p2", Pool: &pool{Size: 20}},
+		Listeners: []listener{{Port: 8080}},
+		Backends:  map[string]*pool{"a": {Size: 1}, "c": {Size: 3}},
+	}
+
+	changes := Diff(old, new)
+	assert.Equal(t, []Change{
+		{Path: "level", Kind: ChangeModified, Old: "info", New: "debug"},
+		{Path: "tags", Kind: ChangeModified, Old: []interface{}{"a"}, New: []interface{}{"a", "b"}},
+		{Path: "token", Kind: ChangeModified, Old: "******", New: "******"},
+		{Path: "database.password", Kind: ChangeModified, Old: "******", New: "******"},
+		{Path: "database.pool.size", Kind: ChangeModified, Old: int64(0), New: int64(20)},
+		{Path: "listeners[0].port", Kind: ChangeModified, Old: int64(80), New: int64(8080)},
+		{Path: "listeners[1].port", Kind: ChangeRemoved, Old: int64(443)},
+		{Path: "backends[b]", Kind: ChangeRemoved},
+		{Path: "backends[c].size", Kind: ChangeAdded, New: int64(3)},
+	}, changes)
+
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	assert.Equal(t, []string{
+		"level: info -> debug",
+		`tags: ["a"] -> ["a","b"]`,
+		"token: ****** -> ******",
+		"database.password: ****** -> ******",
+		"database.pool.size: 0 -> 20",
+		"listeners[0].port: 80 -> 8080",
+		"listeners[1].port: removed 443",
+		"backends[b]: removed null",
+		"backends[c].size: added 3",
+	}, lines)
+
+	assert.Nil(t, old.Database.Pool, "the configs are left alone")
+	assert.Empty(t, Diff(new, new))
+	assert.Len(t, Diff((*example)(nil), new), 10)
+	assert.Nil(t, Diff(old, &pool{}))
+	assert.Nil(t, Diff(*old, *new))
+}
@@ -0,0 +1,314 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DumpFormat is the format of Configurator.Dump.
+type DumpFormat string
+
+const (
+	// DumpYAML renders the config as YAML, with the sources in comments.
+	DumpYAML DumpFormat = "yaml"
+	// DumpJSON renders the config as JSON, each value being an object of
+	// the value and its source.
+	DumpJSON DumpFormat = "json"
+	// DumpTable renders a table of the paths, the values and the sources.
+	DumpTable DumpFormat = "table"
+)
+
+// Dump renders the config of the last Load in format, annotating every value
+// with its origin and masking the secrets.
+func (c *Configurator) Dump(w io.Writer, format DumpFormat) error {
+	c.mu.RLock()
+	v, o := c.loaded, c.origins
+	c.mu.RUnlock()
+	if v == nil {
+		return fmt.Errorf("Configurator/Dump: %w", ErrNotLoaded)
+	}
+
+	// walkStruct allocates the nil pointers and Element stores the map
+	// elements, so a copy is walked rather than the config readers may hold.
+	rv := reflect.ValueOf(v)
+	cp := reflect.New(rv.Type().Elem())
+	cp.Elem().Set(deepCopy(rv.Elem(), c.conv))
+	si, err := walkStruct(cp.Interface(), nil, c.conv, nil)
+	if err != nil {
+		return err
+	}
+	root := &dumpNode{}
+	if err := root.add(si.Fields(), o.last()); err != nil {
+		return err
+	}
+
+	switch format {
+	case DumpYAML:
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+		if err := e.Encode(root.yaml()); err != nil {
+			return err
+		}
+		return e.Close()
+	case DumpJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(root.json())
+	case DumpTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PATH\tVALUE\tSOURCE")
+		root.table(tw)
+		return tw.Flush()
+	default:
+		return fmt.Errorf("Configurator/Dump: %w format [%s]", ErrUnsupported, format)
+	}
+}
+
+// dumpNode is a node of the tree of the fields rendered by Dump. A leaf holds
+// a value, any other node the fields of a struct or the elements of a
+// collection of structs.
+type dumpNode struct {
+	key      string
+	path     string
+	isSeq    bool
+	children []*dumpNode
+	isLeaf   bool
+	value    interface{}
+	source   string
+}
+
+// child returns the child at key, appending it if missing.
+func (n *dumpNode) child(key, path string) *dumpNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	c := &dumpNode{key: key, path: path}
+	n.children = append(n.children, c)
+	return c
+}
+
+// add adds the fields, and the fields of the elements of their collections,
+// at the paths of their lineages.
+func (n *dumpNode) add(fields []FieldInfo, origins map[string]Origin) error {
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		node := n.at(f)
+		if f.elem == nil {
+			node.isLeaf = true
+			node.value = dumpValue(f.val, f.options())
+			if f.isSecret() && !f.val.IsZero() {
+				node.value = secretMask
+			}
+			if o, ok := origins[node.path]; ok {
+				node.source = strings.TrimSpace(o.Source + " " + o.Key)
+			}
+			continue
+		}
+
+		node.isSeq = f.val.Kind() == reflect.Slice
+		for _, key := range f.Keys() {
+			if nilElement(f.val, key) {
+				node.child(key, node.path+"["+key+"]").isLeaf = true
+				continue
+			}
+			err := f.Element(key, func(si StructInfo) error {
+				return n.add(si.Fields(), origins)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// at returns the node of f, creating the nodes of its lineage.
+func (n *dumpNode) at(f *fieldInfo) *dumpNode {
+	node := n
+	path := ""
+	for _, p := range f.lineage() {
+		if p.isElem {
+			path += "[" + p.key + "]"
+			node = node.child(p.key, path)
+			continue
+		}
+		name := strings.ToLower(p.Name())
+		path = joinPath(path, name)
+		node = node.child(name, path)
+	}
+	return node
+}
+
+func (n *dumpNode) yaml() *yaml.Node {
+	if n.isLeaf {
+		var y yaml.Node
+		if err := y.Encode(n.value); err != nil {
+			y = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(n.value)}
+		}
+		if n.source != "" {
+			y.LineComment = n.source
+		}
+		return &y
+	}
+	if n.isSeq {
+		y := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, c := range n.children {
+			y.Content = append(y.Content, c.yaml())
+		}
+		return y
+	}
+	y := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, c := range n.children {
+		k, v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: c.key}, c.yaml()
+		if v.Kind != yaml.ScalarNode {
+			// the comment of a block collection goes on its key
+			k.LineComment, v.LineComment = v.LineComment, ""
+		}
+		y.Content = append(y.Content, k, v)
+	}
+	return y
+}
+
+// dumpJSONValue is a value rendered by Dump as JSON.
+type dumpJSONValue struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source,omitempty"`
+}
+
+func (n *dumpNode) json() interface{} {
+	if n.isLeaf {
+		return dumpJSONValue{Value: n.value, Source: n.source}
+	}
+	if n.isSeq {
+		l := make([]interface{}, len(n.children))
+		for i, c := range n.children {
+			l[i] = c.json()
+		}
+		return l
+	}
+	return dumpJSONObject(n.children)
+}
+
+// dumpJSONObject renders the fields of a struct, keeping their order.
+type dumpJSONObject []*dumpNode
+
+func (o dumpJSONObject) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, c := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, err := json.Marshal(c.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(c.json())
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+func (n *dumpNode) table(w io.Writer) {
+	if !n.isLeaf {
+		for _, c := range n.children {
+			c.table(w)
+		}
+		return
+	}
+	value := formatValue(n.value)
+	source := n.source
+	if source == "" {
+		source = "-"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\n", n.path, value, source)
+}
+
+// formatValue formats a value of dumpValue on a line, a string as it is and
+// anything else as JSON.
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// dumpValue returns v as a value of the basic types, rendered the same way
+// by every format. The time, text and []byte values are formatted the way
+// the providers parse them.
+func dumpValue(v reflect.Value, opts valueOptions) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	typ := v.Type()
+	switch {
+	case typ == timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	case typ == durationType:
+		return time.Duration(v.Int()).String()
+	case isTextType(typ) || typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType):
+		return textString(v)
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		return encodeBytes(v.Bytes(), opts)
+	case len(opts.enum) > 0 && isIndexEnum(typ, opts.enum, opts):
+		// an integer of names holds the index of its value, see setEnumValue
+		if i := int(v.Convert(reflect.TypeOf(0)).Int()); i >= 0 && i < len(opts.enum) {
+			return opts.enum[i]
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			l[i] = dumpValue(v.Index(i), opts.elem())
+		}
+		return l
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = dumpValue(iter.Value(), opts.elem())
+		}
+		return m
+	}
+	return fmt.Sprint(v.Interface())
+}
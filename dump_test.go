@@ -0,0 +1,151 @@
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	type database struct {
+		Host     string `yaml:"host"`
+		Password string `yaml:"password" config:"env,secret"`
+		Token    string `config:"secret"`
+	}
+	type listener struct {
+		Port int `yaml:"port" config:"default=80"`
+	}
+	type example struct {
+		Name      string        `yaml:"name"`
+		Level     int           `config:"env,oneof=debug|info"`
+		Timeout   time.Duration `yaml:"timeout"`
+		Limit     *ByteSize     `config:"default=1MiB"`
+		Tags      []string      `yaml:"tags"`
+		Database  database      `yaml:"database"`
+		Listeners []listener    `yaml:"listeners"`
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`name: svc
+timeout: 90s
+tags: [a, b]
+database:
+  host: db
+listeners:
+  - port: 8080
+  - {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("APP_LEVEL", "info")
+	os.Setenv("APP_DATABASE_PASSWORD", "hunter2")
+	defer os.Unsetenv("APP_LEVEL")
+	defer os.Unsetenv("APP_DATABASE_PASSWORD")
+
+	c := NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP"), WithDefaultProvider())
+	err = c.Dump(&bytes.Buffer{}, DumpYAML)
+	assert.True(t, errors.Is(err, ErrNotLoaded))
+	assert.NoError(t, c.Load(&example{}))
+
+	var b bytes.Buffer
+	assert.NoError(t, c.Dump(&b, DumpYAML))
+	assert.Equal(t, fmt.Sprintf(`name: svc # file %[1]s:1:7
+level: info # env APP_LEVEL
+timeout: 1m30s # file %[1]s:2:10
+limit: 1MiB # default
+tags: # file %[1]s:3:7
+  - a
+  - b
+database:
+  host: db # file %[1]s:5:9
+  password: '******' # env APP_DATABASE_PASSWORD
+  token: ""
+listeners:
+  - port: 8080 # file %[1]s:7:11
+  - port: 80 # default
+`, f.Name()), b.String())
+
+	b.Reset()
+	assert.NoError(t, c.Dump(&b, DumpJSON))
+	assert.Contains(t, b.String(), `"level": {
+    "value": "info",
+    "source": "env APP_LEVEL"
+  },`)
+	assert.Contains(t, b.String(), `"password": {
+      "value": "******",`)
+	assert.NotContains(t, b.String(), "hunter2")
+
+	b.Reset()
+	assert.NoError(t, c.Dump(&b, DumpTable))
+	assert.Equal(t, fmt.Sprintf(`PATH               VALUE      SOURCE
+name               svc        file %[1]s:1:7
+level              info       env APP_LEVEL
+timeout            1m30s      file %[1]s:2:10
+limit              1MiB       default
+tags               ["a","b"]  file %[1]s:3:7
+database.host      db         file %[1]s:5:9
+database.password  ******     env APP_DATABASE_PASSWORD
+database.token                -
+listeners[0].port  8080       file %[1]s:7:11
+listeners[1].port  80         default
+`, f.Name()), b.String())
+
+	assert.True(t, errors.Is(c.Dump(&b, "xml"), ErrUnsupported))
+}
+
+func TestDump_LeavesConfig(t *testing.T) {
+	type pool struct {
+		Size int
+	}
+	type example struct {
+		Pool     *pool
+		Backends map[string]*pool
+	}
+
+	c := NewConfigurator(WithFileProvider(""))
+	cfg := &example{}
+	assert.NoError(t, c.Load(cfg))
+	cfg.Pool, cfg.Backends = nil, map[string]*pool{"a": nil}
+	assert.NoError(t, c.Dump(&bytes.Buffer{}, DumpTable))
+	assert.Nil(t, cfg.Pool)
+	assert.Equal(t, map[string]*pool{"a": nil}, cfg.Backends)
+}
+
+func TestDump_NumericEnum(t *testing.T) {
+	type example struct {
+		Port  int `config:"env,oneof=1|2|4"`
+		Level int `config:"env,oneof=debug|info"`
+	}
+
+	os.Setenv("APP_PORT", "2")
+	os.Setenv("APP_LEVEL", "info")
+	defer os.Unsetenv("APP_PORT")
+	defer os.Unsetenv("APP_LEVEL")
+
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"))
+	assert.NoError(t, c.Load(&example{}))
+	var b bytes.Buffer
+	assert.NoError(t, c.Dump(&b, DumpTable))
+	assert.Equal(t, `PATH   VALUE  SOURCE
+port   2      env APP_PORT
+level  info   env APP_LEVEL
+`, b.String())
+
+	changes := Diff(&example{Port: 2}, &example{Port: 4, Level: 1})
+	var lines []string
+	for _, ch := range changes {
+		lines = append(lines, ch.String())
+	}
+	assert.Equal(t, []string{"port: 2 -> 4", "level: debug -> info"}, lines)
+}
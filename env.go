@@ -1,7 +1,11 @@
 package configurator
 
 import (
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -16,18 +20,137 @@ func NewENVProvider(prefix string) *envProvider {
 }
 
 func (p envProvider) Provide(v interface{}, si StructInfo) error {
-	for _, fi := range si.Fields() {
-		k := p.normalize(fi.ENVKey())
+	var errs fieldErrors
+	p.provide(si.Fields(), "", &errs)
+	return errs.err()
+}
+
+// provide sets the fields from the environment. The keys of the fields are
+// prefixed with base, which is the key of the enclosing element for fields of
+// slices or maps of structs.
+func (p envProvider) provide(fields []FieldInfo, base string, errs *fieldErrors) {
+	for _, fi := range fields {
+		k := fi.ENVKey()
 		if k == "" {
 			continue
 		}
+		if base == "" {
+			k = p.normalize(k)
+		} else {
+			k = strings.Join([]string{base, k}, "_")
+		}
+
+		if fi.Elem() != nil {
+			p.provideElements(fi, k, errs)
+			continue
+		}
+
 		val, ok := os.LookupEnv(k)
 		if !ok {
 			continue
 		}
-		_ = val // TODO set value
+		if err := setFieldValue(fi.Value(), fi.StructField().Type, val, fi.options()); err != nil {
+			errs.add(fi, SourceEnv, k, val, err)
+			continue
+		}
+		fi.setOrigin(SourceEnv, k, val)
+	}
+}
+
+// provideElements builds a slice of structs from indexed keys like
+// `APP_BACKENDS_0_HOST` and `APP_BACKENDS_1_PORT`, or a map of structs from
+// keys like `APP_DBS_PRIMARY_HOST`, whose map key is lower-cased.
+func (p envProvider) provideElements(fi FieldInfo, k string, errs *fieldErrors) {
+	prefix := k + "_"
+	var keys []string
+	var err error
+	if fi.Value().Kind() == reflect.Slice {
+		keys, err = p.sliceKeys(fi, prefix)
+	} else {
+		keys = p.mapKeys(fi, prefix)
+	}
+	if err != nil {
+		errs.add(fi, SourceEnv, k, "", err)
+		return
+	}
+
+	for _, key := range keys {
+		err := fi.Element(strings.ToLower(key), func(si StructInfo) error {
+			p.provide(si.Fields(), prefix+key, errs)
+			return nil
+		})
+		if err != nil {
+			errs.add(fi, SourceEnv, prefix+key, key, err)
+		}
+	}
+}
+
+// sliceKeys returns the indexes found after prefix, reporting the ones missing
+// from both the environment and the slice.
+func (p envProvider) sliceKeys(fi FieldInfo, prefix string) ([]string, error) {
+	seen := make(map[int]struct{})
+	for _, name := range envNames(prefix) {
+		rest := strings.TrimPrefix(name, prefix)
+		i := strings.Index(rest, "_")
+		if i <= 0 {
+			continue
+		}
+		idx, err := strconv.Atoi(rest[:i])
+		if err != nil || idx < 0 {
+			continue
+		}
+		seen[idx] = struct{}{}
+	}
+
+	indexes := make([]int, 0, len(seen))
+	for idx := range seen {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	if len(indexes) > 0 {
+		for i := fi.Value().Len(); i < indexes[len(indexes)-1]; i++ {
+			if _, ok := seen[i]; !ok {
+				return nil, fmt.Errorf("envProvider/sliceKeys: %w [%s%d]", ErrMissingIndex, prefix, i)
+			}
+		}
+	}
+
+	keys := make([]string, len(indexes))
+	for i, idx := range indexes {
+		keys[i] = strconv.Itoa(idx)
+	}
+	return keys, nil
+}
+
+// mapKeys returns the map keys found between prefix and the key of
+// an element field.
+func (p envProvider) mapKeys(fi FieldInfo, prefix string) []string {
+	seen := make(map[string]struct{})
+	for _, name := range envNames(prefix) {
+		rest := strings.TrimPrefix(name, prefix)
+		for _, ef := range fi.Elem().Fields() {
+			ek := ef.ENVKey()
+			if ek == "" {
+				continue
+			}
+			i := strings.Index(rest, "_"+ek)
+			if i <= 0 {
+				continue
+			}
+			if tail := rest[i+len(ek)+1:]; tail != "" && !strings.HasPrefix(tail, "_") {
+				continue
+			}
+			seen[rest[:i]] = struct{}{}
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
 	}
-	return nil
+	sort.Strings(keys)
+	return keys
 }
 
 func (p envProvider) normalize(key string) string {
@@ -39,3 +162,18 @@ func (p envProvider) normalize(key string) string {
 	}
 	return strings.Join([]string{p.prefix, key}, "_")
 }
+
+// envNames returns the names of the environment variables starting with prefix.
+func envNames(prefix string) []string {
+	var names []string
+	for _, kv := range os.Environ() {
+		name := kv
+		if i := strings.Index(kv, "="); i >= 0 {
+			name = kv[:i]
+		}
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,371 @@
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestENVProvider_Map(t *testing.T) {
+	type example struct {
+		Labels map[string]string `config:"env"`
+		Ports  map[string]uint16 `config:"env=APP_PORTS,sep=;,kvsep=:"`
+	}
+
+	os.Setenv("APP_LABELS", "a=1,b=2")
+	os.Setenv("APP_PORTS", "http:80;https:443")
+	defer os.Unsetenv("APP_LABELS")
+	defer os.Unsetenv("APP_PORTS")
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Labels: map[string]string{"a": "1", "b": "2"},
+		Ports:  map[string]uint16{"http": 80, "https": 443},
+	}, cfg)
+}
+
+func TestENVProvider_MapInvalidEntry(t *testing.T) {
+	type example struct {
+		Labels map[string]string `config:"env"`
+	}
+
+	os.Setenv("APP_LABELS", "a=1,b")
+	defer os.Unsetenv("APP_LABELS")
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.True(t, errors.Is(err, ErrEmptyValue))
+}
+
+func TestENVProvider_Slice(t *testing.T) {
+	type example struct {
+		Bs    []bool          `config:"env"`
+		Is    []int           `config:"env"`
+		I64s  []int64         `config:"env"`
+		Ds    []time.Duration `config:"env"`
+		Us    []uint          `config:"env"`
+		U64s  []uint64        `config:"env"`
+		F32s  []float32       `config:"env"`
+		F64s  []float64       `config:"env"`
+		Ss    []string        `config:"env"`
+		Quote []string        `config:"env"`
+		Semi  []string        `config:"env,sep=;"`
+		Ts    []time.Time     `config:"env"`
+		Bytes []byte          `config:"env"`
+	}
+
+	envs := map[string]string{
+		"APP_BS":    "true,0,1",
+		"APP_IS":    "1, 2,3",
+		"APP_I64S":  "4,5",
+		"APP_DS":    "2s,5m",
+		"APP_US":    "5,6",
+		"APP_U64S":  "6,7",
+		"APP_F32S":  "7.5,8",
+		"APP_F64S":  "8,9.25",
+		"APP_SS":    "abc,defg",
+		"APP_QUOTE": `a,"b,c",d`,
+		"APP_SEMI":  "a,b;c",
+		"APP_TS":    "2020-09-30T22:51:49-08:00,2021-09-30T22:51:49-08:00",
+		"APP_BYTES": "AQIDBAoL",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Bs:    []bool{true, false, true},
+		Is:    []int{1, 2, 3},
+		I64s:  []int64{4, 5},
+		Ds:    []time.Duration{2 * time.Second, 5 * time.Minute},
+		Us:    []uint{5, 6},
+		U64s:  []uint64{6, 7},
+		F32s:  []float32{7.5, 8},
+		F64s:  []float64{8, 9.25},
+		Ss:    []string{"abc", "defg"},
+		Quote: []string{"a", "b,c", "d"},
+		Semi:  []string{"a,b", "c"},
+		Ts: []time.Time{
+			time.Date(2020, 9, 30, 22, 51, 49, 0, time.FixedZone("", -28800)),
+			time.Date(2021, 9, 30, 22, 51, 49, 0, time.FixedZone("", -28800)),
+		},
+		Bytes: []byte{0x01, 0x02, 0x03, 0x04, 0x0a, 0x0b},
+	}, cfg)
+}
+
+func TestENVProvider_StructSlice(t *testing.T) {
+	type backend struct {
+		Host   string `config:"env"`
+		Port   int    `config:"env"`
+		Weight *int   `config:"env"`
+	}
+	type example struct {
+		Backends []backend           `config:"env"`
+		Pointers []*backend          `config:"env"`
+		DBs      map[string]*backend `config:"env"`
+	}
+
+	envs := map[string]string{
+		"APP_BACKENDS_0_HOST":    "a",
+		"APP_BACKENDS_0_PORT":    "80",
+		"APP_BACKENDS_1_HOST":    "b",
+		"APP_POINTERS_0_PORT":    "81",
+		"APP_DBS_PRIMARY_HOST":   "db1",
+		"APP_DBS_PRIMARY_PORT":   "5432",
+		"APP_DBS_READ_ONE_HOST":  "db2",
+		"APP_DBS_READ_ONE_PORT":  "5433",
+		"APP_DBS_READ_ONE_OTHER": "ignored",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := &example{Backends: []backend{{Host: "x", Port: 1}, {Host: "y", Port: 2}}}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Backends: []backend{{Host: "a", Port: 80}, {Host: "b", Port: 2}},
+		Pointers: []*backend{{Port: 81}},
+		DBs: map[string]*backend{
+			"primary":  {Host: "db1", Port: 5432},
+			"read_one": {Host: "db2", Port: 5433},
+		},
+	}, cfg)
+}
+
+func TestENVProvider_StructSliceGap(t *testing.T) {
+	type backend struct {
+		Host string `config:"env"`
+	}
+	type example struct {
+		Backends []backend `config:"env"`
+	}
+
+	os.Setenv("APP_BACKENDS_0_HOST", "a")
+	os.Setenv("APP_BACKENDS_2_HOST", "c")
+	defer os.Unsetenv("APP_BACKENDS_0_HOST")
+	defer os.Unsetenv("APP_BACKENDS_2_HOST")
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.True(t, errors.Is(err, ErrMissingIndex))
+}
+
+func TestENVProvider_Text(t *testing.T) {
+	type example struct {
+		Level  logLevel            `config:"env"`
+		IP     *net.IP             `config:"env"`
+		Levels map[string]logLevel `config:"env"`
+		Upper  upperValue          `config:"env"`
+	}
+
+	envs := map[string]string{
+		"APP_LEVEL":  "info",
+		"APP_IP":     "10.0.0.1",
+		"APP_LEVELS": "http=debug,db=info",
+		"APP_UPPER":  "abc",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.NoError(t, err)
+
+	ip := net.ParseIP("10.0.0.1")
+	assert.Equal(t, &example{
+		Level:  logLevel(1),
+		IP:     &ip,
+		Levels: map[string]logLevel{"http": 0, "db": 1},
+		Upper:  upperValue("ABC"),
+	}, cfg)
+
+	os.Setenv("APP_LEVEL", "trace")
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.Error(t, err)
+}
+
+func TestENVProvider_OutOfRange(t *testing.T) {
+	type example struct {
+		I8ptr *int8  `config:"env"`
+		U32   uint32 `config:"env"`
+	}
+
+	tests := []struct {
+		env    map[string]string
+		expect *example
+		err    bool
+	}{
+		{env: map[string]string{"APP_I8PTR": "-5", "APP_U32": "4294967295"}, expect: &example{I8ptr: i8(-5), U32: 4294967295}},
+		{env: map[string]string{"APP_I8PTR": "128"}, err: true},
+		{env: map[string]string{"APP_U32": "4294967296"}, err: true},
+	}
+
+	for _, tt := range tests {
+		for k, v := range tt.env {
+			os.Setenv(k, v)
+		}
+		cfg := &example{}
+		si, err := getStructInfo(cfg, nil)
+		assert.NoError(t, err)
+
+		err = NewENVProvider("APP").Provide(cfg, si)
+		if tt.err {
+			assert.True(t, errors.Is(err, ErrOutOfRange), "%v", err)
+			assert.Contains(t, err.Error(), "APP_")
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, cfg)
+		}
+		for k := range tt.env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestENVProvider_LenientBool(t *testing.T) {
+	type example struct {
+		A bool  `config:"env"`
+		B *bool `config:"env"`
+		C bool  `config:"env,flag"`
+	}
+
+	os.Setenv("APP_A", "Yes")
+	os.Setenv("APP_B", "off")
+	defer os.Unsetenv("APP_A")
+	defer os.Unsetenv("APP_B")
+	os.Args = []string{"cmd", "-c"}
+	resetForTesting()
+
+	cfg := &example{}
+	err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithFlagProvider(), WithLenientBool()).Load(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{A: true, B: b(false), C: true}, cfg)
+
+	os.Args = []string{"cmd"}
+	resetForTesting()
+	err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(&example{})
+	assert.Error(t, err)
+
+	os.Setenv("APP_A", "maybe")
+	err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithLenientBool()).Load(&example{})
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.Contains(t, err.Error(), "yes")
+}
+
+type testLevel int
+
+func TestENVProvider_Enum(t *testing.T) {
+	type example struct {
+		Mode   string    `config:"env,enum=dev|staging|prod"`
+		Level  testLevel `config:"env,oneof=debug|info|warn"`
+		Index  *int      `config:"env,enum=a|b"`
+		Sinks  []string  `config:"env,enum=stdout|file"`
+		Parsed logLevel  `config:"env,enum=debug|info"`
+	}
+
+	envs := map[string]string{
+		"APP_MODE":   "PROD",
+		"APP_LEVEL":  "Info",
+		"APP_INDEX":  "1",
+		"APP_SINKS":  "stdout,FILE",
+		"APP_PARSED": "INFO",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+	err = NewENVProvider("APP").Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{Mode: "prod", Level: 1, Index: i(1), Sinks: []string{"stdout", "file"}, Parsed: 1}, cfg)
+
+	for k, v := range map[string]string{"APP_MODE": "test", "APP_LEVEL": "3", "APP_SINKS": "stdout,syslog"} {
+		os.Setenv(k, v)
+		err = NewENVProvider("APP").Provide(&example{}, si)
+		assert.True(t, errors.Is(err, ErrNotAllowed), "%s: %v", k, err)
+		os.Setenv(k, envs[k])
+	}
+	os.Setenv("APP_MODE", "test")
+	err = NewENVProvider("APP").Provide(&example{}, si)
+	assert.Contains(t, err.Error(), "allowed values are [dev, staging, prod]")
+}
+
+func TestENVProvider_NumericEnum(t *testing.T) {
+	type example struct {
+		Workers int     `yaml:"workers" config:"env,oneof=1|2|4"`
+		Mask    uint8   `yaml:"mask" config:"env,oneof=0x10|0x20"`
+		Ratio   float64 `yaml:"ratio" config:"env,oneof=0.5|1"`
+	}
+
+	os.Setenv("APP_WORKERS", "4")
+	os.Setenv("APP_MASK", "32")
+	os.Setenv("APP_RATIO", "0.50")
+	defer os.Unsetenv("APP_WORKERS")
+	defer os.Unsetenv("APP_MASK")
+	defer os.Unsetenv("APP_RATIO")
+
+	cfg := &example{}
+	assert.NoError(t, NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(cfg))
+	assert.Equal(t, &example{Workers: 4, Mask: 32, Ratio: 0.5}, cfg)
+
+	os.Setenv("APP_WORKERS", "3")
+	err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(&example{})
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.Contains(t, err.Error(), "allowed values are [1, 2, 4]")
+	os.Unsetenv("APP_WORKERS")
+	os.Unsetenv("APP_MASK")
+	os.Unsetenv("APP_RATIO")
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("workers: 4\nmask: 16\nratio: 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = &example{}
+	assert.NoError(t, NewConfigurator(WithFileProvider(f.Name())).Load(cfg))
+	assert.Equal(t, &example{Workers: 4, Mask: 16, Ratio: 1}, cfg)
+
+	assert.NoError(t, ioutil.WriteFile(f.Name(), []byte("workers: 3\n"), 0600))
+	err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+}
@@ -2,6 +2,8 @@ package configurator
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
@@ -11,4 +13,103 @@ var (
 	ErrEmptyKey         = errors.New("empty key")
 	ErrConflictKey      = errors.New("conflict key")
 	ErrUnsupported      = errors.New("unsupported")
+	ErrMissingArg       = errors.New("missing positional argument")
+	ErrMissingIndex     = errors.New("missing index")
+	ErrOutOfRange       = errors.New("value out of range")
+	ErrNotAllowed       = errors.New("value not allowed")
+	ErrInvalidLength    = errors.New("invalid length")
+	ErrValidation       = errors.New("validation failed")
+	ErrRequired         = errors.New("required value missing")
+	ErrPatternMismatch  = errors.New("value does not match pattern")
+	ErrUnknownKey       = errors.New("unknown key")
+	ErrTypeMismatch     = errors.New("type mismatch")
+	ErrNotLoaded        = errors.New("config not loaded")
+	ErrUnknownPath      = errors.New("unknown path")
 )
+
+// FieldError is a value a provider could not set into a field.
+type FieldError struct {
+	// Path is the path of the field, see FieldInfo.Path, empty if unknown.
+	Path string
+	// Source is the provider of the value, one of the Source constants.
+	Source string
+	// Key is the environment variable, the flag, the index of the positional
+	// argument or the file the value comes from.
+	Key      string
+	RawValue string
+	Err      error
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString("[" + e.Path + "] ")
+	}
+	b.WriteString(e.Source)
+	if e.Key != "" {
+		b.WriteString(" " + e.Key)
+	}
+	return fmt.Sprintf("%s: %v", b.String(), e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError holds every FieldError of the providers of a Load. errors.Is and
+// errors.As match each of them in turn.
+type LoadError struct {
+	Errors []FieldError
+}
+
+func (e *LoadError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *LoadError) Is(target error) bool {
+	for i := range e.Errors {
+		if errors.Is(&e.Errors[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *LoadError) As(target interface{}) bool {
+	for i := range e.Errors {
+		if errors.As(&e.Errors[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldErrors collects the FieldErrors of a provider, so that it goes on with
+// the other fields.
+type fieldErrors []FieldError
+
+func (e *fieldErrors) add(fi FieldInfo, source, key, raw string, err error) {
+	fe := FieldError{Source: source, Key: key, RawValue: raw, Err: err}
+	if fi != nil {
+		fe.Path = fi.Path()
+	}
+	if f, ok := fi.(*fieldInfo); ok && f.isSecret() && raw != "" {
+		fe.RawValue, fe.Err = secretMask, hideSecret(err)
+	}
+	*e = append(*e, fe)
+}
+
+// err returns a LoadError of the errors, or nil if there is none.
+func (e fieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return &LoadError{Errors: e}
+}
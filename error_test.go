@@ -0,0 +1,70 @@
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadError(t *testing.T) {
+	type example struct {
+		Timeout string `json:"timeout" config:"env"`
+		Wait    int    `json:"wait" config:"default=soon"`
+		Port    int    `json:"port" config:"env"`
+		Level   string `json:"level" config:"flag,oneof=debug|info"`
+		Workers int8   `json:"workers" config:"flag"`
+		Debug   bool   `json:"debug" config:"flag"`
+		Target  string `json:"target" config:"arg=0"`
+		Retries []int  `json:"retries" config:"args"`
+	}
+
+	f, err := ioutil.TempFile("", "*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"timeout":"1s","port":"http"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_PORT", "eighty")
+	defer os.Unsetenv("APP_PORT")
+	os.Args = []string{"cmd", "-level=trace", "-workers=300", "-debug", "host", "1", "x"}
+	resetForTesting()
+
+	cfg := &example{}
+	err = NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP"), WithFlagProvider(), WithDefaultProvider()).Load(cfg)
+
+	var le *LoadError
+	assert.True(t, errors.As(err, &le))
+	var got [][3]string
+	for _, fe := range le.Errors {
+		got = append(got, [3]string{fe.Path, fe.Source, fe.Key + "=" + fe.RawValue})
+	}
+	assert.Equal(t, [][3]string{
+		{"port", SourceFile, f.Name() + ":1:24=http"},
+		{"port", SourceEnv, "APP_PORT=eighty"},
+		{"level", SourceFlag, "level=trace"},
+		{"workers", SourceFlag, "workers=300"},
+		{"retries", SourceArg, "2=x"},
+		{"wait", SourceDefault, "=soon"},
+	}, got)
+
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	assert.False(t, errors.Is(err, ErrMissingArg))
+	var ne *strconv.NumError
+	assert.True(t, errors.As(err, &ne))
+	var fe *FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, SourceFile, fe.Source)
+	assert.Contains(t, err.Error(), "6 errors: ")
+	assert.Contains(t, err.Error(), "[workers] flag workers: ")
+
+	assert.Equal(t, &example{}, cfg, "a failed load leaves the config alone")
+}
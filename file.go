@@ -1,11 +1,18 @@
 package configurator
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,9 +23,12 @@ func NewFileProvider(filename string) *fileProvider {
 
 type fileProvider struct {
 	filename string
+	// strict reports the keys of the file matching no field, like
+	// DisallowUnknownFields and KnownFields(true) would, but all of them.
+	strict bool
 }
 
-func (p fileProvider) Provide(v interface{}, _ StructInfo) error {
+func (p fileProvider) Provide(v interface{}, si StructInfo) error {
 	f, err := os.Open(p.filename)
 	if err != nil {
 		return err
@@ -27,18 +37,562 @@ func (p fileProvider) Provide(v interface{}, _ StructInfo) error {
 		_ = f.Close()
 	}()
 
-	var d decoder
 	switch strings.ToLower(filepath.Ext(p.filename)) {
 	case ".json":
-		d = json.NewDecoder(f)
+		return p.decodeJSON(f, v, si)
 	case ".yaml", ".yml":
-		d = yaml.NewDecoder(f)
+		return p.decodeYAML(f, v, si)
 	default:
 		return fmt.Errorf("the specified file %s is %w", p.filename, ErrUnsupported)
 	}
-	return d.Decode(v)
 }
 
-type decoder interface {
-	Decode(interface{}) error
+// location returns the position of a value in the file, e.g. `config.yaml:3:5`.
+func (p fileProvider) location(line, column int) string {
+	return fmt.Sprintf("%s:%d:%d", p.filename, line, column)
+}
+
+// addUnknownKeys adds an error for each of the unknown keys.
+func (p fileProvider) addUnknownKeys(errs *fieldErrors, keys []unknownKey) {
+	for _, k := range keys {
+		*errs = append(*errs, FieldError{
+			Path:   k.path,
+			Source: SourceFile,
+			Key:    p.location(k.line, k.column),
+			Err:    k.err(),
+		})
+	}
+}
+
+// fileOrigins returns the origins of the fields set by the file, found
+// calling walk. The structs, except the leaf types, are left to their fields.
+func (p fileProvider) fileOrigins(walk func(*fileVisitor), conv *converters) []Origin {
+	var l []Origin
+	walk(&fileVisitor{field: func(fn fileNode, typ reflect.Type) {
+		if t := keyedType(typ); t != nil && !isLeafType(typ, conv) && (t.Kind() == reflect.Struct || structElemType(t, conv) != nil) {
+			return
+		}
+		l = append(l, Origin{Path: fn.path, Source: SourceFile, Key: p.location(fn.line, fn.column), RawValue: fn.raw})
+	}})
+	return l
+}
+
+// recordOrigins records the origins into the recorder of si, if any.
+func recordOrigins(si StructInfo, l []Origin) {
+	o := originsOf(si)
+	for _, origin := range l {
+		o.record(origin)
+	}
+}
+
+// addJSONError adds err, with its position if it is a syntax error.
+func (p fileProvider) addJSONError(errs *fieldErrors, data []byte, err error) {
+	key := p.filename
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		// Offset is past the offending byte
+		key = p.location(lineColumn(data, int(se.Offset)-1))
+	}
+	errs.add(nil, SourceFile, key, "", err)
+}
+
+// addDecodeErrors adds an error for each value of the file failing to decode
+// into its field, found calling walk, or err itself if there is none. The
+// time, duration and []byte values are left to the rewriting of the leaves.
+func (p fileProvider) addDecodeErrors(errs *fieldErrors, err error, walk func(*fileVisitor)) {
+	n := len(*errs)
+	walk(&fileVisitor{leaf: func(fn fileNode, typ reflect.Type) {
+		isTime, isDuration := timeKind(typ)
+		if isTime || isDuration || typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		if err := fn.decode(reflect.New(typ).Interface()); err != nil {
+			*errs = append(*errs, FieldError{
+				Path:     fn.path,
+				Source:   SourceFile,
+				Key:      p.location(fn.line, fn.column),
+				RawValue: fn.raw,
+				Err:      typeMismatch(fn.raw, typ, err),
+			})
+		}
+	}})
+	if len(*errs) == n {
+		errs.add(nil, SourceFile, p.filename, "", err)
+	}
+}
+
+// typeMismatch reports raw failing to decode into typ, dropping the type
+// errors of the decoders, which name Go types only.
+func typeMismatch(raw string, typ reflect.Type, err error) error {
+	var yte *yaml.TypeError
+	var jte *json.UnmarshalTypeError
+	if errors.As(err, &yte) || errors.As(err, &jte) {
+		return fmt.Errorf("%w, cannot decode [%s] as [%s]", ErrTypeMismatch, raw, typ)
+	}
+	return fmt.Errorf("%w, cannot decode [%s] as [%s]: %v", ErrTypeMismatch, raw, typ, err)
+}
+
+func (p fileProvider) decodeJSON(f *os.File, v interface{}, si StructInfo) error {
+	if si == nil && !p.strict {
+		return json.NewDecoder(f).Decode(v)
+	}
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var errs fieldErrors
+	if p.strict {
+		keys, err := unknownJSONKeys(data, reflect.TypeOf(v))
+		if err != nil {
+			p.addJSONError(&errs, data, err)
+			return errs.err()
+		}
+		p.addUnknownKeys(&errs, keys)
+	}
+	walk := func(vis *fileVisitor) {
+		_ = walkJSON(data, reflect.TypeOf(v), vis)
+	}
+	if si == nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			p.addDecodeErrors(&errs, err, walk)
+		}
+		return errs.err()
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var tree interface{}
+	if err := d.Decode(&tree); err != nil {
+		p.addJSONError(&errs, data, err)
+		return errs.err()
+	}
+	converted := convertedValues(walk, si.Fields())
+	for _, fi := range convertedLeaves(si.Fields()) {
+		tree, _ = rewriteJSON(tree, "", fi.lineage(), func(_ string, raw interface{}) (interface{}, error) {
+			if _, ok := raw.([]interface{}); ok {
+				return raw, nil
+			}
+			if _, ok := raw.(map[string]interface{}); ok {
+				return raw, nil
+			}
+			return nil, nil
+		})
+	}
+	positions := make(map[string]fileNode)
+	walk(&fileVisitor{field: func(n fileNode, _ reflect.Type) {
+		positions[n.path] = n
+	}})
+	for _, fi := range fileLeaves(si.Fields()) {
+		fi := fi
+		tree, _ = rewriteJSON(tree, "", fi.lineage(), func(path string, raw interface{}) (interface{}, error) {
+			v, err := rewriteJSONLeaf(fi, raw)
+			if err != nil {
+				key := p.filename
+				if n, ok := positions[path]; ok {
+					key = p.location(n.line, n.column)
+				}
+				errs.add(fi, SourceFile, key, fmt.Sprint(raw), err)
+				return raw, nil
+			}
+			return v, nil
+		})
+	}
+	if len(errs) > 0 {
+		return errs.err()
+	}
+	rewritten, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rewritten, v); err != nil {
+		p.addDecodeErrors(&errs, err, walk)
+		return errs.err()
+	}
+	p.setConverted(&errs, si.Fields(), converted)
+	recordOrigins(si, p.fileOrigins(walk, convertersOf(si)))
+	return errs.err()
+}
+
+func (p fileProvider) decodeYAML(f *os.File, v interface{}, si StructInfo) error {
+	if si == nil && !p.strict {
+		return yaml.NewDecoder(f).Decode(v)
+	}
+
+	var errs fieldErrors
+	var n yaml.Node
+	if err := yaml.NewDecoder(f).Decode(&n); err != nil {
+		errs.add(nil, SourceFile, p.filename, "", err)
+		return errs.err()
+	}
+	if p.strict {
+		var keys []unknownKey
+		unknownYAMLKeys(&n, reflect.TypeOf(v), &keys)
+		p.addUnknownKeys(&errs, keys)
+	}
+	walk := func(vis *fileVisitor) {
+		walkYAML(&n, reflect.TypeOf(v), "", vis)
+	}
+	// the origins keep the values as written, before rewriting the leaves
+	written := p.fileOrigins(walk, convertersOf(si))
+	var leaves, convLeaves []*fieldInfo
+	var converted map[string]fileNode
+	if si != nil {
+		leaves, convLeaves = fileLeaves(si.Fields()), convertedLeaves(si.Fields())
+		converted = convertedValues(walk, si.Fields())
+	}
+	for _, fi := range convLeaves {
+		for _, leaf := range yamlNodes(&n, fi.lineage()) {
+			if leaf.Kind == yaml.ScalarNode {
+				leaf.Tag, leaf.Value, leaf.Style = "!!null", "null", 0
+			}
+		}
+	}
+	for _, fi := range leaves {
+		for _, leaf := range yamlNodes(&n, fi.lineage()) {
+			if err := rewriteYAMLLeaf(fi, leaf); err != nil {
+				errs.add(fi, SourceFile, p.location(leaf.Line, leaf.Column), leaf.Value, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs.err()
+	}
+	if err := n.Decode(v); err != nil {
+		p.addDecodeErrors(&errs, err, walk)
+		return errs.err()
+	}
+	if si != nil {
+		p.setConverted(&errs, si.Fields(), converted)
+	}
+	recordOrigins(si, written)
+	return errs.err()
+}
+
+// fileLeaves returns the time, duration and []byte fields, including the ones
+// of element templates, whose values in a file are rewritten into the formats
+// the decoders understand, so that they accept the formats of parseTime,
+// parseDuration and decodeBytes like the other providers.
+func fileLeaves(fields []FieldInfo) []*fieldInfo {
+	var leaves []*fieldInfo
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		if f.elem != nil {
+			leaves = append(leaves, fileLeaves(f.elem.Fields())...)
+			continue
+		}
+		if isConvertedField(f) {
+			continue
+		}
+		if isTime, isDuration := timeKind(f.field.Type); isTime || isDuration || isBytesField(f) {
+			leaves = append(leaves, f)
+		}
+	}
+	return leaves
+}
+
+// convertedLeaves returns the fields set through a converter, see
+// RegisterConverter, or holding the index of a `oneof` name, see isIndexEnum,
+// including the ones of element templates. The decoders know neither: the
+// values of these fields are decoded as null, then set by setConverted the
+// way the other providers set them.
+func convertedLeaves(fields []FieldInfo) []*fieldInfo {
+	var leaves []*fieldInfo
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		if f.elem != nil {
+			leaves = append(leaves, convertedLeaves(f.elem.Fields())...)
+			continue
+		}
+		if isConvertedField(f) {
+			leaves = append(leaves, f)
+		}
+	}
+	return leaves
+}
+
+// isConvertedField reports whether f is set through a converter, or holds
+// the index of a `oneof` name.
+func isConvertedField(f *fieldInfo) bool {
+	if _, ok := findConverter(f.conv, f.field.Type); ok {
+		return true
+	}
+	typ := f.field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	opts := f.options()
+	return len(opts.enum) > 0 && isIndexEnum(typ, opts.enum, opts)
+}
+
+// convertedValues returns the values of the file for the fields of
+// convertedLeaves by their paths, found calling walk.
+func convertedValues(walk func(*fileVisitor), fields []FieldInfo) map[string]fileNode {
+	leaves := convertedLeaves(fields)
+	if len(leaves) == 0 {
+		return nil
+	}
+	paths := make(map[string]bool, len(leaves))
+	for _, f := range leaves {
+		paths[f.Path()] = true
+	}
+	values := make(map[string]fileNode)
+	walk(&fileVisitor{leaf: func(n fileNode, typ reflect.Type) {
+		if paths[pathTemplate(n.path)] {
+			values[n.path] = n
+		}
+	}})
+	return values
+}
+
+// setConverted sets the fields set through a converter, and the ones of the
+// elements of the collections, from their values in the file.
+func (p fileProvider) setConverted(errs *fieldErrors, fields []FieldInfo, values map[string]fileNode) {
+	if len(values) == 0 {
+		return
+	}
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		if f.elem != nil {
+			for _, key := range f.Keys() {
+				if nilElement(f.val, key) {
+					continue
+				}
+				_ = f.Element(key, func(si StructInfo) error {
+					p.setConverted(errs, si.Fields(), values)
+					return nil
+				})
+			}
+			continue
+		}
+		n, ok := values[f.Path()]
+		if !ok || !isConvertedField(f) {
+			continue
+		}
+		raw := n.raw
+		if n.secret != "" {
+			raw = n.secret
+		}
+		if err := setFieldValue(f.val, f.field.Type, raw, f.options()); err != nil {
+			errs.add(f, SourceFile, p.location(n.line, n.column), raw, err)
+		}
+	}
+}
+
+// timeKind reports whether typ is a time or a duration, or a pointer or a
+// slice of them.
+func timeKind(typ reflect.Type) (isTime bool, isDuration bool) {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	return typ == timeType, typ == durationType
+}
+
+// isBytesField reports whether f is a SecretBytes, or a []byte with an
+// `encoding` or a `len` tag.
+func isBytesField(f *fieldInfo) bool {
+	typ := f.field.Type
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() != reflect.Uint8 || isLeafType(typ, f.conv) {
+		return false
+	}
+	return f.tag.encoding != "" || f.tag.hasLen || typ == secretBytesType
+}
+
+func yamlKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func jsonKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// yamlNodes returns the nodes under n at the path of lineage, following every
+// element of the collections.
+func yamlNodes(n *yaml.Node, lineage []*fieldInfo) []*yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if len(lineage) == 0 {
+		return []*yaml.Node{n}
+	}
+
+	var nodes []*yaml.Node
+	f := lineage[0]
+	switch {
+	case f.isElem && n.Kind == yaml.SequenceNode:
+		for _, c := range n.Content {
+			nodes = append(nodes, yamlNodes(c, lineage[1:])...)
+		}
+	case f.isElem && n.Kind == yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			nodes = append(nodes, yamlNodes(n.Content[i], lineage[1:])...)
+		}
+	case !f.isElem && n.Kind == yaml.MappingNode:
+		key := yamlKey(f.field)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				nodes = append(nodes, yamlNodes(n.Content[i+1], lineage[1:])...)
+			}
+		}
+	}
+	return nodes
+}
+
+// rewriteYAMLLeaf rewrites the time, duration or []byte scalars of n into
+// RFC 3339 timestamps, Go durations or sequences of bytes.
+func rewriteYAMLLeaf(fi *fieldInfo, n *yaml.Node) error {
+	if isBytesField(fi) {
+		if n.Kind != yaml.ScalarNode || n.ShortTag() == "!!null" {
+			return nil
+		}
+		b, err := decodeBytes(n.Value, fi.options())
+		if err != nil {
+			return err
+		}
+		// yaml.v3 decodes a []byte only from a sequence of integers.
+		n.Kind, n.Tag, n.Value, n.Style, n.Content = yaml.SequenceNode, "!!seq", "", yaml.FlowStyle, make([]*yaml.Node, len(b))
+		for i, c := range b {
+			n.Content[i] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(int(c))}
+		}
+		return nil
+	}
+	if n.Kind == yaml.SequenceNode {
+		for _, c := range n.Content {
+			if err := rewriteYAMLLeaf(fi, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if n.Kind != yaml.ScalarNode || n.ShortTag() == "!!null" {
+		return nil
+	}
+
+	isTime, _ := timeKind(fi.field.Type)
+	if isTime {
+		t, err := parseTime(n.Value, fi.tag.layout)
+		if err != nil {
+			return err
+		}
+		n.Value, n.Tag, n.Style = t.Format(time.RFC3339Nano), "!!timestamp", 0
+		return nil
+	}
+	d, err := parseDuration(n.Value)
+	if err != nil {
+		return err
+	}
+	n.Value, n.Tag, n.Style = d.String(), "!!str", 0
+	return nil
+}
+
+// rewriteJSON replaces the values under n, found at path, at the path of
+// lineage with the results of fn, called with their paths, following every
+// element of the collections.
+func rewriteJSON(n interface{}, path string, lineage []*fieldInfo, fn func(string, interface{}) (interface{}, error)) (interface{}, error) {
+	if len(lineage) == 0 {
+		return fn(path, n)
+	}
+
+	var err error
+	f := lineage[0]
+	switch c := n.(type) {
+	case []interface{}:
+		if !f.isElem {
+			return n, nil
+		}
+		for i := range c {
+			if c[i], err = rewriteJSON(c[i], fmt.Sprintf("%s[%d]", path, i), lineage[1:], fn); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		key := jsonKey(f.field)
+		for k := range c {
+			if !f.isElem && !strings.EqualFold(k, key) {
+				continue
+			}
+			p := fmt.Sprintf("%s[%s]", path, k)
+			if !f.isElem {
+				p = joinPath(path, strings.ToLower(f.Name()))
+			}
+			if c[k], err = rewriteJSON(c[k], p, lineage[1:], fn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// rewriteJSONLeaf rewrites the time, duration or []byte values in raw into
+// RFC 3339 strings, nanoseconds or standard base64. A number is read as epoch
+// seconds for a time, see parseTime, and as nanoseconds for a duration.
+func rewriteJSONLeaf(fi *fieldInfo, raw interface{}) (interface{}, error) {
+	if isBytesField(fi) {
+		s, ok := raw.(string)
+		if !ok {
+			return raw, nil
+		}
+		b, err := decodeBytes(s, fi.options())
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+	if items, ok := raw.([]interface{}); ok {
+		for i := range items {
+			v, err := rewriteJSONLeaf(fi, items[i])
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+	}
+
+	isTime, _ := timeKind(fi.field.Type)
+	switch v := raw.(type) {
+	case json.Number:
+		if !isTime {
+			return v, nil
+		}
+		t, err := parseTime(v.String(), fi.tag.layout)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(time.RFC3339Nano), nil
+	case string:
+		if isTime {
+			t, err := parseTime(v, fi.tag.layout)
+			if err != nil {
+				return nil, err
+			}
+			return t.Format(time.RFC3339Nano), nil
+		}
+		d, err := parseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatInt(int64(d), 10)), nil
+	}
+	return raw, nil
 }
@@ -2,9 +2,11 @@ package configurator
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -102,3 +104,318 @@ func TestFileLoader_UnsupportError(t *testing.T) {
 func int64ptr(i int64) *int64 {
 	return &i
 }
+
+func TestFileProvider_Strict(t *testing.T) {
+	type pool struct {
+		MaxConns int `json:"max_conns" yaml:"max_conns"`
+	}
+	type inner struct {
+		Region string `json:"region" yaml:"region"`
+	}
+	type database struct {
+		Host string `json:"host" yaml:"host"`
+		Pool pool   `json:"pool" yaml:"pool"`
+	}
+	type example struct {
+		inner    `yaml:",inline"`
+		Database database          `json:"database" yaml:"database"`
+		Replicas []database        `json:"replicas" yaml:"replicas"`
+		Labels   map[string]string `json:"labels" yaml:"labels"`
+		Ignored  string            `json:"-" yaml:"-"`
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		expect  []string
+	}{
+		{
+			file: "*.yaml",
+			content: `region: eu
+databse:
+  host: a
+database:
+  host: b
+  pool:
+    maxconns: 3
+replicas:
+  - host: c
+    hots: d
+labels:
+  any: key
+ignored: x
+`,
+			expect: []string{
+				"[databse] file %s:2:1: unknown key [databse], did you mean [database]?",
+				"[database.pool.maxconns] file %s:7:5: unknown key [maxconns], did you mean [max_conns]?",
+				"[replicas[0].hots] file %s:10:5: unknown key [hots], did you mean [host]?",
+				"[ignored] file %s:13:1: unknown key [ignored]",
+			},
+		},
+		{
+			file: "*.json",
+			content: `{
+  "Region": "eu",
+  "database": {"host": "b", "pool": {"maxconns": 3}},
+  "replicas": [{"host": "c"}, {"hots": "d"}],
+  "labels": {"any": "key"},
+  "timeout": 3
+}`,
+			expect: []string{
+				"[database.pool.maxconns] file %s:3:38: unknown key [maxconns], did you mean [max_conns]?",
+				"[replicas[1].hots] file %s:4:32: unknown key [hots], did you mean [host]?",
+				"[timeout] file %s:6:3: unknown key [timeout]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := ioutil.TempFile("", tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, err = f.WriteString(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &example{}
+			err = NewConfigurator(WithFileProvider(f.Name())).Load(cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, "b", cfg.Database.Host)
+
+			err = NewConfigurator(WithFileProvider(f.Name()), WithStrictFile()).Load(&example{})
+			assert.True(t, errors.Is(err, ErrUnknownKey))
+			var le *LoadError
+			assert.True(t, errors.As(err, &le))
+			var msgs []string
+			for _, fe := range le.Errors {
+				msgs = append(msgs, fe.Error())
+			}
+			for i := range tt.expect {
+				tt.expect[i] = fmt.Sprintf(tt.expect[i], f.Name())
+			}
+			assert.Equal(t, tt.expect, msgs)
+		})
+	}
+}
+
+func TestFileProvider_DecodeErrors(t *testing.T) {
+	type backend struct {
+		Host   string   `json:"host" yaml:"host"`
+		Weight int      `json:"weight" yaml:"weight"`
+		Limit  ByteSize `json:"limit" yaml:"limit"`
+	}
+	type example struct {
+		Name     string             `json:"name" yaml:"name"`
+		Port     int                `json:"port" yaml:"port"`
+		Backends []backend          `json:"backends" yaml:"backends"`
+		Pools    map[string]backend `json:"pools" yaml:"pools"`
+		Debug    bool               `json:"debug" yaml:"debug"`
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		expect  []string
+	}{
+		{
+			file: "*.yaml",
+			content: `name: svc
+port: http
+backends:
+  - host: a
+    weight: heavy
+pools:
+  main:
+    limit: 3XB
+debug: [true]
+`,
+			expect: []string{
+				"[port] file %s:2:7: type mismatch, cannot decode [http] as [int]",
+				"[backends[0].weight] file %s:5:13: type mismatch, cannot decode [heavy] as [int]",
+				"[pools[main].limit] file %s:8:12: type mismatch, cannot decode [3XB] as [configurator.ByteSize]: ParseByteSize: unknown unit [xb] in [3XB]",
+				"[debug] file %s:9:8: type mismatch, cannot decode [] as [bool]",
+			},
+		},
+		{
+			file: "*.json",
+			content: `{
+  "name": 1,
+  "backends": [{"host": "a"}, {"weight": "heavy"}],
+  "pools": {"main": {"limit": "3XB"}}
+}`,
+			expect: []string{
+				"[name] file %s:2:11: type mismatch, cannot decode [1] as [string]",
+				"[backends[1].weight] file %s:3:42: type mismatch, cannot decode [heavy] as [int]",
+				"[pools[main].limit] file %s:4:31: type mismatch, cannot decode [3XB] as [configurator.ByteSize]: ParseByteSize: unknown unit [xb] in [3XB]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := ioutil.TempFile("", tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, err = f.WriteString(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+			assert.True(t, errors.Is(err, ErrTypeMismatch))
+			var le *LoadError
+			assert.True(t, errors.As(err, &le))
+			var msgs []string
+			for _, fe := range le.Errors {
+				msgs = append(msgs, fe.Error())
+			}
+			for i := range tt.expect {
+				tt.expect[i] = fmt.Sprintf(tt.expect[i], f.Name())
+			}
+			assert.Equal(t, tt.expect, msgs)
+		})
+	}
+
+	f, err := ioutil.TempFile("", "*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("{\n  \"name\": \"a\",\n  \"port\" 1\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+	assert.EqualError(t, err, fmt.Sprintf("file %s:3:10: invalid character '1' after object key", f.Name()))
+}
+
+type EmbeddedBase struct {
+	Port    int           `yaml:"port" config:"default=80"`
+	Host    string        `yaml:"host" config:"required"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func TestFileProvider_EmbeddedOrigins(t *testing.T) {
+	type example struct {
+		EmbeddedBase
+		Name string `yaml:"name"`
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("embeddedbase:\n  port: 0\n  host: db\n  timeout: 1m\nname: svc\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConfigurator(WithFileProvider(f.Name()), WithDefaultProvider())
+	cfg := &example{}
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, &example{EmbeddedBase: EmbeddedBase{Host: "db", Timeout: time.Minute}, Name: "svc"}, cfg)
+	assert.Equal(t, fmt.Sprintf(`port: file %s:2:9="0"`, f.Name()), c.Explain("port"))
+	assert.Equal(t, fmt.Sprintf(`timeout: file %s:4:12="1m"`, f.Name()), c.Explain("timeout"))
+}
+
+func TestFileProvider_Enum(t *testing.T) {
+	type sink struct {
+		Level int `yaml:"level" json:"level" config:"oneof=debug|info|warn"`
+	}
+	type example struct {
+		Level  int    `yaml:"level" json:"level" config:"oneof=debug|info|warn"`
+		Max    *int   `yaml:"max" json:"max" config:"oneof=debug|info|warn"`
+		Sinks  []sink `yaml:"sinks" json:"sinks"`
+		Number int    `yaml:"number" json:"number" config:"oneof=debug|info|warn"`
+	}
+
+	for ext, content := range map[string]string{
+		".yaml": "level: info\nmax: WARN\nsinks:\n  - level: warn\nnumber: 1\n",
+		".json": `{"level": "info", "max": "WARN", "sinks": [{"level": "warn"}], "number": 1}`,
+	} {
+		f, err := ioutil.TempFile("", "*"+ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := &example{}
+		assert.NoError(t, NewConfigurator(WithFileProvider(f.Name())).Load(cfg), ext)
+		assert.Equal(t, &example{Level: 1, Max: i(2), Sinks: []sink{{Level: 2}}, Number: 1}, cfg, ext)
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("level: trace\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.Contains(t, err.Error(), f.Name()+":1:8")
+}
+
+func TestFileProvider_RewriteErrors(t *testing.T) {
+	type backend struct {
+		Since time.Time `json:"since" yaml:"since"`
+	}
+	type example struct {
+		Timeout  time.Duration `json:"timeout" yaml:"timeout"`
+		Backends []backend     `json:"backends" yaml:"backends"`
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		expect  []string
+	}{
+		{
+			file:    "*.yaml",
+			content: "timeout: abc\nbackends:\n  - since: x\n",
+			expect:  []string{"[timeout] file %s:1:10", "[backends[].since] file %s:3:12"},
+		},
+		{
+			file:    "*.json",
+			content: "{\"timeout\": \"abc\",\n \"backends\": [{\"since\": \"x\"}]}",
+			expect:  []string{"[timeout] file %s:1:13", "[backends[].since] file %s:2:25"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := ioutil.TempFile("", tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, err = f.WriteString(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+			var le *LoadError
+			if assert.True(t, errors.As(err, &le)) && assert.Len(t, le.Errors, len(tt.expect)) {
+				for i, e := range tt.expect {
+					assert.Contains(t, le.Errors[i].Error(), fmt.Sprintf(e, f.Name()))
+				}
+			}
+		})
+	}
+}
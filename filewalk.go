@@ -0,0 +1,359 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileNode is a value of a file found walking it along the type of the
+// config.
+type fileNode struct {
+	path         string
+	line, column int
+	// raw is the text of a scalar, masked for a secret.
+	raw string
+	// secret is the text of a secret, whose raw is masked.
+	secret string
+	// decode decodes the value into the value its argument points to.
+	decode func(interface{}) error
+}
+
+// fileVisitor is called back walking a file, see walkYAML and walkJSON.
+type fileVisitor struct {
+	// unknown is called with a key matching no field, and the known keys.
+	unknown func(key fileNode, known []string)
+	// leaf is called with a value decoded as a whole into typ, or not
+	// matching the kind of typ.
+	leaf func(n fileNode, typ reflect.Type)
+	// field is called with the value of every field of a struct, before
+	// walking it.
+	field func(n fileNode, typ reflect.Type)
+}
+
+// secret returns a visitor calling vis back with the values of a secret,
+// their raw texts masked and their decode errors hidden.
+func (vis *fileVisitor) secret() *fileVisitor {
+	mask := func(n fileNode) fileNode {
+		if n.raw != "" {
+			n.secret, n.raw = n.raw, secretMask
+		}
+		if decode := n.decode; decode != nil {
+			n.decode = func(v interface{}) error {
+				return hideSecret(decode(v))
+			}
+		}
+		return n
+	}
+	s := &fileVisitor{unknown: vis.unknown}
+	if vis.leaf != nil {
+		s.leaf = func(n fileNode, typ reflect.Type) {
+			vis.leaf(mask(n), typ)
+		}
+	}
+	if vis.field != nil {
+		s.field = func(n fileNode, typ reflect.Type) {
+			vis.field(mask(n), typ)
+		}
+	}
+	return s
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// keyedType returns the type whose keys are checked for a value of typ, that
+// is a struct, a map, a slice or an array, or nil for the types decoded as a
+// whole.
+func keyedType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || isLeafType(typ, nil) {
+		return nil
+	}
+	for _, t := range []reflect.Type{typ, reflect.PtrTo(typ)} {
+		if t.Implements(jsonUnmarshalerType) || t.Implements(yamlUnmarshalerType) || t.Implements(textUnmarshalerType) {
+			return nil
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return typ
+	}
+	return nil
+}
+
+// fileFields returns the fields of the struct typ by their keys in a file,
+// named by name, following the embedded structs the way the decoders do.
+// inlineMap reports an inline map taking every other key.
+func fileFields(typ reflect.Type, name func(reflect.StructField) string, isInline func(reflect.StructField) bool) (fields map[string]reflect.StructField, inlineMap bool) {
+	fields = make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		key := name(f)
+		if key == "-" {
+			continue
+		}
+		if isInline(f) {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			switch ft.Kind() {
+			case reflect.Map:
+				inlineMap = true
+				continue
+			case reflect.Struct:
+				inner, innerMap := fileFields(ft, name, isInline)
+				for k, v := range inner {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				inlineMap = inlineMap || innerMap
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		fields[key] = f
+	}
+	return fields, inlineMap
+}
+
+func yamlFields(typ reflect.Type) (map[string]reflect.StructField, bool) {
+	return fileFields(typ, yamlKey, func(f reflect.StructField) bool {
+		for _, opt := range strings.Split(f.Tag.Get("yaml"), ",")[1:] {
+			if opt == "inline" {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// jsonFields returns the fields of the struct typ by their lower-cased keys,
+// as encoding/json matches keys case-insensitively.
+func jsonFields(typ reflect.Type) (map[string]reflect.StructField, bool) {
+	fields, inlineMap := fileFields(typ, func(f reflect.StructField) string {
+		return strings.ToLower(jsonKey(f))
+	}, func(f reflect.StructField) bool {
+		return f.Anonymous && strings.Split(f.Tag.Get("json"), ",")[0] == ""
+	})
+	return fields, inlineMap
+}
+
+func knownKeys(fields map[string]reflect.StructField, key func(reflect.StructField) string) []string {
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, key(f))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldPath returns the path of the field f of the struct at path, the same
+// as FieldInfo.Path: an embedded struct is flattened into its parent, even
+// when the file has it under a key of its own.
+func fieldPath(path string, f reflect.StructField) string {
+	typ := f.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if f.Anonymous && typ.Kind() == reflect.Struct && !isLeafType(f.Type, nil) {
+		return path
+	}
+	return joinPath(path, strings.ToLower(f.Name))
+}
+
+// walkYAML walks n along typ, calling vis back.
+func walkYAML(n *yaml.Node, typ reflect.Type, path string, vis *fileVisitor) {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if typ == nil || typ.Kind() == reflect.Interface || n.ShortTag() == "!!null" {
+		return
+	}
+
+	kt := keyedType(typ)
+	switch {
+	case kt == nil:
+	case kt.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields, inlineMap := yamlFields(kt)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				walkYAML(v, kt, path, vis)
+				continue
+			}
+			f, ok := fields[k.Value]
+			if ok {
+				fp := fieldPath(path, f)
+				fvis := vis
+				if isSecretField(f) {
+					fvis = vis.secret()
+				}
+				if fvis.field != nil {
+					fvis.field(fileNode{path: fp, line: v.Line, column: v.Column, raw: v.Value, decode: v.Decode}, f.Type)
+				}
+				walkYAML(v, f.Type, fp, fvis)
+				continue
+			}
+			if !inlineMap && vis.unknown != nil {
+				vis.unknown(fileNode{
+					path: joinPath(path, k.Value), line: k.Line, column: k.Column, raw: k.Value,
+				}, knownKeys(fields, yamlKey))
+			}
+		}
+		return
+	case kt.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			walkYAML(n.Content[i+1], kt.Elem(), fmt.Sprintf("%s[%s]", path, n.Content[i].Value), vis)
+		}
+		return
+	case (kt.Kind() == reflect.Slice || kt.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for i, c := range n.Content {
+			walkYAML(c, kt.Elem(), fmt.Sprintf("%s[%d]", path, i), vis)
+		}
+		return
+	}
+	if vis.leaf != nil {
+		vis.leaf(fileNode{path: path, line: n.Line, column: n.Column, raw: n.Value, decode: n.Decode}, typ)
+	}
+}
+
+// jsonWalker walks a JSON document, keeping it whole for the lines and the
+// columns of the values.
+type jsonWalker struct {
+	data []byte
+	vis  *fileVisitor
+}
+
+// walkJSON walks data along typ, calling vis back.
+func walkJSON(data []byte, typ reflect.Type, vis *fileVisitor) error {
+	w := &jsonWalker{data: data, vis: vis}
+	d := json.NewDecoder(bytes.NewReader(data))
+	var raw json.RawMessage
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+	return w.walk(raw, int(d.InputOffset())-len(raw), typ, "")
+}
+
+// walk walks the value raw found at offset.
+func (w *jsonWalker) walk(raw json.RawMessage, offset int, typ reflect.Type, path string) error {
+	if typ == nil || typ.Kind() == reflect.Interface || len(raw) == 0 || raw[0] == 'n' {
+		return nil
+	}
+
+	kt := keyedType(typ)
+	switch {
+	case kt == nil:
+	case raw[0] == '{' && (kt.Kind() == reflect.Struct || kt.Kind() == reflect.Map):
+		var fields map[string]reflect.StructField
+		var inlineMap bool
+		if kt.Kind() == reflect.Struct {
+			fields, inlineMap = jsonFields(kt)
+		}
+		return w.members(raw, offset, func(key string, keyEnd int, v json.RawMessage, at int) error {
+			if kt.Kind() == reflect.Map {
+				return w.walk(v, at, kt.Elem(), fmt.Sprintf("%s[%s]", path, key))
+			}
+			if f, ok := fields[strings.ToLower(key)]; ok {
+				fp := fieldPath(path, f)
+				fw := w
+				if isSecretField(f) {
+					fw = &jsonWalker{data: w.data, vis: w.vis.secret()}
+				}
+				if fw.vis.field != nil {
+					fw.vis.field(fw.node(fp, v, at), f.Type)
+				}
+				return fw.walk(v, at, f.Type, fp)
+			}
+			if !inlineMap && w.vis.unknown != nil {
+				start := bytes.LastIndexByte(w.data[:keyEnd-1], '"')
+				line, column := lineColumn(w.data, start)
+				w.vis.unknown(fileNode{path: joinPath(path, key), line: line, column: column, raw: key}, knownKeys(fields, jsonKey))
+			}
+			return nil
+		})
+	case raw[0] == '[' && (kt.Kind() == reflect.Slice || kt.Kind() == reflect.Array):
+		i := 0
+		return w.members(raw, offset, func(_ string, _ int, v json.RawMessage, at int) error {
+			i++
+			return w.walk(v, at, kt.Elem(), fmt.Sprintf("%s[%d]", path, i-1))
+		})
+	}
+	if w.vis.leaf != nil {
+		w.vis.leaf(w.node(path, raw, offset), typ)
+	}
+	return nil
+}
+
+// node returns the node of the value raw found at offset. The raw text of a
+// string is unquoted.
+func (w *jsonWalker) node(path string, raw json.RawMessage, offset int) fileNode {
+	line, column := lineColumn(w.data, offset)
+	text := string(raw)
+	_ = json.Unmarshal(raw, &text)
+	return fileNode{path: path, line: line, column: column, raw: text, decode: func(v interface{}) error {
+		return json.Unmarshal(raw, v)
+	}}
+}
+
+// members calls fn with the members of the object, or the elements of the
+// array, raw found at offset, along with the offsets of their keys' ends and
+// of their values.
+func (w *jsonWalker) members(raw json.RawMessage, offset int, fn func(key string, keyEnd int, v json.RawMessage, at int) error) error {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := d.Token(); err != nil {
+		return err
+	}
+	for d.More() {
+		var key string
+		var keyEnd int
+		if raw[0] == '{' {
+			tok, err := d.Token()
+			if err != nil {
+				return err
+			}
+			key, _ = tok.(string)
+			keyEnd = offset + int(d.InputOffset())
+		}
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			return err
+		}
+		if err := fn(key, keyEnd, v, offset+int(d.InputOffset())-len(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineColumn returns the line and the column of offset in data, from 1.
+func lineColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	return line, offset - bytes.LastIndexByte(data[:offset], '\n')
+}
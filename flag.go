@@ -1,16 +1,25 @@
 package configurator
 
 import (
-	"encoding/base64"
 	"flag"
 	"fmt"
+	"os"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type flagProvider struct {
 	flags map[string]func()
+	// parsed is set once the command line is parsed on flag.CommandLine,
+	// along with the values of the flags set and the positional arguments.
+	// A later Provide, reloading the config, replays them rather than parse
+	// the command line again without the other flags of the program.
+	parsed bool
+	set    map[string][]string
+	args   []string
 }
 
 func NewFlagProvider() *flagProvider {
@@ -20,7 +29,26 @@ func NewFlagProvider() *flagProvider {
 }
 
 func (p *flagProvider) Provide(v interface{}, si StructInfo) error {
+	p.flags = make(map[string]func())
+
+	var errs fieldErrors
+	args := make(map[int]FieldInfo)
+	var rest FieldInfo
+	values := make(map[string]*collectValue)
 	for _, fi := range si.Fields() {
+		if idx := fi.ArgIndex(); idx >= 0 {
+			if _, ok := args[idx]; ok {
+				return fmt.Errorf("flagProvider/Provide: %w [arg=%d]", ErrConflictKey, idx)
+			}
+			args[idx] = fi
+		}
+		if fi.RestArgs() {
+			if rest != nil {
+				return fmt.Errorf("flagProvider/Provide: %w [args]", ErrConflictKey)
+			}
+			rest = fi
+		}
+
 		k := fi.FlagKey()
 		if k == "" {
 			continue
@@ -28,380 +56,284 @@ func (p *flagProvider) Provide(v interface{}, si StructInfo) error {
 		if _, ok := p.flags[k]; ok {
 			return fmt.Errorf("flagProvider/Provide: %w [%s]", ErrConflictKey, k)
 		}
-		fn, err := createVarSetFunc(k, fi.Value(), fi.StructField().Type)
+		fv, fn, err := createVarSetFunc(fi.Value(), fi.StructField().Type, fi.options())
 		if err != nil {
 			return err
 		}
-		p.flags[k] = fn
+		cv := &collectValue{Value: fv, fi: fi, key: k, errs: &errs}
+		values[k] = cv
+		p.flags[k] = func() {
+			if !cv.failed {
+				fn()
+				cv.fi.setOrigin(SourceFlag, cv.key, strings.Join(cv.raw, " "))
+			}
+		}
+	}
+	if !p.parsed {
+		if err := p.parse(values); err != nil {
+			return err
+		}
+	} else {
+		p.replay(values)
 	}
-	flag.Parse()
 
-	flag.Visit(func(f *flag.Flag) {
-		if fn, ok := p.flags[f.Name]; ok {
-			fn()
+	bindArgs(p.args, args, rest, &errs)
+	return errs.err()
+}
+
+// parse parses the command line on flag.CommandLine, where the other flags
+// of the program are defined too, and records the values of the flags of
+// the fields and the positional arguments.
+func (p *flagProvider) parse(values map[string]*collectValue) error {
+	fs := flag.CommandLine
+	for k, cv := range values {
+		fs.Var(cv, k, "")
+	}
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return fmt.Errorf("flagProvider/Provide: %w", err)
+	}
+	p.parsed = true
+	p.set = make(map[string][]string)
+	fs.Visit(func(f *flag.Flag) {
+		if cv, ok := values[f.Name]; ok {
+			p.set[f.Name] = cv.raw
+			p.flags[f.Name]()
 		}
 	})
-
+	p.args = fs.Args()
 	return nil
 }
 
-var (
-	durationType    = reflect.TypeOf(time.Duration(0))
-	durationPtrType = reflect.TypeOf((*time.Duration)(nil))
-)
-
-func createVarSetFunc(k string, val reflect.Value, typ reflect.Type) (func(), error) {
-	switch typ.Kind() {
-	case reflect.Bool:
-		v := flag.Bool(k, false, "")
-		return func() { val.SetBool(*v) }, nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
-		v := flag.Int(k, 0, "")
-		return func() { val.SetInt(int64(*v)) }, nil
-	case reflect.Int64:
-		if typ == durationType {
-			v := flag.Duration(k, time.Duration(0), "")
-			return func() { val.SetInt(int64(*v)) }, nil
-		} else {
-			v := flag.Int64(k, 0, "")
-			return func() { val.SetInt(*v) }, nil
+// replay sets the flags of the fields to the values recorded by parse, in
+// the order of fs.Visit.
+func (p *flagProvider) replay(values map[string]*collectValue) {
+	keys := make([]string, 0, len(p.set))
+	for k := range p.set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cv, ok := values[k]
+		if !ok {
+			continue
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
-		v := flag.Uint(k, 0, "")
-		return func() { val.SetUint(uint64(*v)) }, nil
-	case reflect.Uint64:
-		v := flag.Uint64(k, 0, "")
-		return func() { val.SetUint(*v) }, nil
-	case reflect.Float32, reflect.Float64:
-		v := flag.Float64(k, 0, "")
-		return func() { val.SetFloat(*v) }, nil
-	case reflect.String:
-		v := flag.String(k, "", "")
-		return func() { val.SetString(*v) }, nil
-	case reflect.Ptr:
-		return createPtrSetFunc(k, val, typ)
-	case reflect.Slice:
-		return createSliceSetFunc(k, val, typ)
-	case reflect.Struct:
-		if typ == timeType {
-			var v timeValue
-			flag.Var(&v, k, "")
-			return func() {
-				t := time.Time(v)
-				val.Set(reflect.ValueOf(t))
-			}, nil
+		for _, raw := range p.set[k] {
+			_ = cv.Set(raw)
 		}
-		return nil, fmt.Errorf("flagProvider/createVarSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
-	default:
-		return nil, fmt.Errorf("flagProvider/createVarSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
+		p.flags[k]()
 	}
 }
 
-func createPtrSetFunc(k string, val reflect.Value, typ reflect.Type) (func(), error) {
-	switch typ.Elem().Kind() {
-	case reflect.Bool:
-		v := flag.Bool(k, false, "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.Int:
-		v := flag.Int(k, 0, "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.Int8:
-		v := flag.Int(k, 0, "")
-		return func() {
-			i8 := int8(*v)
-			val.Set(reflect.ValueOf(&i8))
-		}, nil
-	case reflect.Int16:
-		v := flag.Int(k, 0, "")
-		return func() {
-			i16 := int16(*v)
-			val.Set(reflect.ValueOf(&i16))
-		}, nil
-	case reflect.Int32:
-		v := flag.Int(k, 0, "")
-		return func() {
-			i32 := int32(*v)
-			val.Set(reflect.ValueOf(&i32))
-		}, nil
-	case reflect.Int64:
-		if typ == durationPtrType {
-			v := flag.Duration(k, time.Duration(0), "")
-			return func() {
-				val.Set(reflect.ValueOf(v))
-			}, nil
-		} else {
-			v := flag.Int64(k, 0, "")
-			return func() {
-				val.Set(reflect.ValueOf(v))
-			}, nil
-		}
-	case reflect.Uint:
-		v := flag.Uint(k, 0, "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.Uint8:
-		v := flag.Uint(k, 0, "")
-		return func() {
-			u8 := uint8(*v)
-			val.Set(reflect.ValueOf(&u8))
-		}, nil
-	case reflect.Uint16:
-		v := flag.Uint(k, 0, "")
-		return func() {
-			u16 := uint16(*v)
-			val.Set(reflect.ValueOf(&u16))
-		}, nil
-	case reflect.Uint32:
-		v := flag.Uint(k, 0, "")
-		return func() {
-			u32 := uint32(*v)
-			val.Set(reflect.ValueOf(&u32))
-		}, nil
-	case reflect.Uint64:
-		v := flag.Uint64(k, 0, "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.Float32:
-		v := flag.Float64(k, 0, "")
-		return func() {
-			f32 := float32(*v)
-			val.Set(reflect.ValueOf(&f32))
-		}, nil
-	case reflect.Float64:
-		v := flag.Float64(k, 0, "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.String:
-		v := flag.String(k, "", "")
-		return func() {
-			val.Set(reflect.ValueOf(v))
-		}, nil
-	case reflect.Struct:
-		if typ == timePtrType {
-			var v timeValue
-			flag.Var(&v, k, "")
-			return func() {
-				t := time.Time(v)
-				val.Set(reflect.ValueOf(&t))
-			}, nil
-		}
-		return nil, fmt.Errorf("flagProvider/createPtrSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
-	default:
-		return nil, fmt.Errorf("flagProvider/createPtrSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
-	}
+// collectValue reports the errors of a flag to the provider rather than to
+// flag.Parse, which would stop at the first one. The field of a flag failing
+// to parse is left alone.
+type collectValue struct {
+	flag.Value
+	fi     FieldInfo
+	key    string
+	errs   *fieldErrors
+	failed bool
+	// raw holds the values of the flag, which may be repeated.
+	raw []string
 }
 
-func createSliceSetFunc(k string, val reflect.Value, typ reflect.Type) (func(), error) {
-	switch typ.Elem().Kind() {
-	case reflect.Bool:
-		var v boolSliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Int:
-		var v intSliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Int64:
-		if typ.Elem() == durationType {
-			var v durationSliceValue
-			flag.Var(&v, k, "")
-			return func() { val.Set(reflect.ValueOf(v)) }, nil
-		} else {
-			var v int64SliceValue
-			flag.Var(&v, k, "")
-			return func() { val.Set(reflect.ValueOf(v)) }, nil
-		}
-	case reflect.Uint:
-		var v uintSliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Uint8:
-		var v base64StringValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Uint64:
-		var v uint64SliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Float32:
-		var v float32SliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Float64:
-		var v float64SliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.String:
-		var v stringSliceValue
-		flag.Var(&v, k, "")
-		return func() { val.Set(reflect.ValueOf(v)) }, nil
-	case reflect.Struct:
-		if typ.Elem() == timeType {
-			var v timeSliceValue
-			flag.Var(&v, k, "")
-			return func() { val.Set(reflect.ValueOf(v)) }, nil
-		}
-		return nil, fmt.Errorf("flagProvider/createSliceSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
-	default:
-		return nil, fmt.Errorf("flagProvider/createSliceSetFunc: %w type [%s]", ErrUnsupported, typ.Kind().String())
+func (c *collectValue) String() string {
+	if c == nil || c.Value == nil {
+		return ""
 	}
+	return c.Value.String()
 }
 
-type timeValue time.Time
-
-func (t *timeValue) String() string { return time.Time(*t).String() }
-
-func (t *timeValue) Set(v string) error {
-	tv, err := time.Parse(time.RFC3339, v)
-	if err != nil {
-		return err
+func (c *collectValue) Set(s string) error {
+	c.raw = append(c.raw, s)
+	if err := c.Value.Set(s); err != nil {
+		c.errs.add(c.fi, SourceFlag, c.key, s, err)
+		c.failed = true
 	}
-	*t = timeValue(tv)
 	return nil
 }
 
-type boolSliceValue []bool
+func (c *collectValue) IsBoolFlag() bool {
+	b, ok := c.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
 
-func (b *boolSliceValue) String() string { return fmt.Sprintf("%v", []bool(*b)) }
+// bindArgs sets the positional arguments to the fields tagged with `arg=N`,
+// and the ones after the last indexed argument to the field tagged with `args`.
+// An indexed argument without a default value is required.
+func bindArgs(values []string, args map[int]FieldInfo, rest FieldInfo, errs *fieldErrors) {
+	indexes := make([]int, 0, len(args))
+	for idx := range args {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	n := 0
+	for _, idx := range indexes {
+		fi := args[idx]
+		n = idx + 1
+		if idx >= len(values) {
+			if fi.DefVal() != "" {
+				continue
+			}
+			errs.add(fi, SourceArg, strconv.Itoa(idx), "", ErrMissingArg)
+			continue
+		}
+		if err := setFieldValue(fi.Value(), fi.StructField().Type, values[idx], fi.options()); err != nil {
+			errs.add(fi, SourceArg, strconv.Itoa(idx), values[idx], err)
+			continue
+		}
+		fi.setOrigin(SourceArg, strconv.Itoa(idx), values[idx])
+	}
 
-func (b *boolSliceValue) Set(s string) error {
-	v, err := strconv.ParseBool(s)
-	if err != nil {
-		return err
+	if rest == nil || n >= len(values) {
+		return
 	}
-	*b = append(*b, v)
-	return nil
+	typ := rest.StructField().Type
+	s := reflect.MakeSlice(typ, 0, len(values)-n)
+	for i, a := range values[n:] {
+		e := reflect.New(typ.Elem()).Elem()
+		if err := setFieldValue(e, typ.Elem(), a, rest.options()); err != nil {
+			errs.add(rest, SourceArg, strconv.Itoa(n+i), a, err)
+			return
+		}
+		s = reflect.Append(s, e)
+	}
+	rest.Value().Set(s)
+	rest.setOrigin(SourceArg, strconv.Itoa(n), strings.Join(values[n:], " "))
 }
 
-type intSliceValue []int
-
-func (i *intSliceValue) String() string { return fmt.Sprintf("%v", []int(*i)) }
+var durationType = reflect.TypeOf(time.Duration(0))
 
-func (i *intSliceValue) Set(s string) error {
-	v, err := strconv.ParseInt(s, 0, strconv.IntSize)
-	if err != nil {
-		return err
+// createVarSetFunc returns the flag.Value of a field of type typ, and the
+// function setting val from it once the flags are parsed.
+func createVarSetFunc(val reflect.Value, typ reflect.Type, opts valueOptions) (flag.Value, func(), error) {
+	if isScalarType(typ, opts) {
+		v := newFieldValue(typ, opts)
+		return v, func() { val.Set(v.v) }, nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		return createSliceSetFunc(val, typ, opts)
+	case reflect.Map:
+		v := &mapValue{m: reflect.MakeMap(typ), opts: opts}
+		return v, func() { val.Set(v.m) }, nil
+	default:
+		return nil, nil, fmt.Errorf("flagProvider/createVarSetFunc: %w type [%s]", ErrUnsupported, typ.String())
 	}
-	*i = append(*i, int(v))
-	return nil
 }
 
-type int64SliceValue []int64
-
-func (i *int64SliceValue) String() string { return fmt.Sprintf("%v", []int64(*i)) }
-
-func (i *int64SliceValue) Set(s string) error {
-	v, err := strconv.ParseInt(s, 0, 64)
-	if err != nil {
-		return err
+func createSliceSetFunc(val reflect.Value, typ reflect.Type, opts valueOptions) (flag.Value, func(), error) {
+	if typ.Elem().Kind() == reflect.Uint8 {
+		v := &bytesValue{opts: opts}
+		return v, func() { val.SetBytes(v.b) }, nil
 	}
-	*i = append(*i, v)
-	return nil
+	if !isScalarType(typ.Elem(), opts.elem()) {
+		return nil, nil, fmt.Errorf("flagProvider/createSliceSetFunc: %w type [%s]", ErrUnsupported, typ.String())
+	}
+	v := &fieldSliceValue{v: reflect.MakeSlice(typ, 0, 0), opts: opts.elem()}
+	return v, func() { val.Set(v.v) }, nil
 }
 
-type durationSliceValue []time.Duration
+// fieldValue is a flag of a single value, converted by setFieldValue like
+// the values of the other providers. It is displayed through the
+// encoding.TextMarshaler of the type if any.
+type fieldValue struct {
+	v    reflect.Value
+	opts valueOptions
+}
 
-func (d *durationSliceValue) String() string { return fmt.Sprintf("%v", []time.Duration(*d)) }
+func newFieldValue(typ reflect.Type, opts valueOptions) *fieldValue {
+	return &fieldValue{v: reflect.New(typ).Elem(), opts: opts}
+}
 
-func (d *durationSliceValue) Set(s string) error {
-	v, err := time.ParseDuration(s)
-	if err != nil {
-		return err
+func (f *fieldValue) String() string {
+	if f == nil || !f.v.IsValid() {
+		return ""
 	}
-	*d = append(*d, v)
-	return nil
+	return textString(f.v)
 }
 
-type uintSliceValue []uint
-
-func (u *uintSliceValue) String() string { return fmt.Sprintf("%v", []uint(*u)) }
+func (f *fieldValue) Set(s string) error {
+	return setFieldValue(f.v, f.v.Type(), s, f.opts)
+}
 
-func (u *uintSliceValue) Set(s string) error {
-	v, err := strconv.ParseUint(s, 0, strconv.IntSize)
-	if err != nil {
-		return err
+// IsBoolFlag lets a bool flag be set without a value, like `-debug`.
+func (f *fieldValue) IsBoolFlag() bool {
+	if f == nil || !f.v.IsValid() {
+		return false
 	}
-	*u = append(*u, uint(v))
-	return nil
+	typ := f.v.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Bool && !isTextType(typ)
 }
 
-type uint64SliceValue []uint64
-
-func (u *uint64SliceValue) String() string { return fmt.Sprintf("%v", []uint64(*u)) }
+// fieldSliceValue collects the values of a repeated flag, each converted by
+// setFieldValue.
+type fieldSliceValue struct {
+	v    reflect.Value
+	opts valueOptions
+}
 
-func (u *uint64SliceValue) Set(s string) error {
-	v, err := strconv.ParseUint(s, 0, 64)
-	if err != nil {
-		return err
+func (f *fieldSliceValue) String() string {
+	if f == nil || !f.v.IsValid() {
+		return ""
 	}
-	*u = append(*u, v)
-	return nil
+	items := make([]string, f.v.Len())
+	for i := range items {
+		items[i] = textString(f.v.Index(i))
+	}
+	return fmt.Sprintf("%v", items)
 }
 
-type float32SliceValue []float32
-
-func (f *float32SliceValue) String() string { return fmt.Sprintf("%v", []float32(*f)) }
-
-func (f *float32SliceValue) Set(s string) error {
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
+func (f *fieldSliceValue) Set(s string) error {
+	e := reflect.New(f.v.Type().Elem()).Elem()
+	if err := setFieldValue(e, e.Type(), s, f.opts); err != nil {
 		return err
 	}
-	*f = append(*f, float32(v))
+	f.v = reflect.Append(f.v, e)
 	return nil
 }
 
-type float64SliceValue []float64
-
-func (f *float64SliceValue) String() string { return fmt.Sprintf("%v", []float64(*f)) }
+// mapValue collects the entries of repeated flags like `-label a=1 -label b=2`
+// into a map.
+type mapValue struct {
+	m    reflect.Value
+	opts valueOptions
+}
 
-func (f *float64SliceValue) Set(s string) error {
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return err
+func (m *mapValue) String() string {
+	if !m.m.IsValid() {
+		return ""
 	}
-	*f = append(*f, v)
-	return nil
+	return fmt.Sprintf("%v", m.m.Interface())
 }
 
-type stringSliceValue []string
-
-func (s *stringSliceValue) String() string { return fmt.Sprintf("%v", []string(*s)) }
-
-func (s *stringSliceValue) Set(v string) error {
-	*s = append(*s, v)
-	return nil
+func (m *mapValue) Set(s string) error {
+	return putMapEntries(m.m, s, m.opts)
 }
 
-type base64StringValue []byte
-
-func (b *base64StringValue) String() string { return base64.StdEncoding.EncodeToString([]byte(*b)) }
+// bytesValue is a []byte flag decoded with the `encoding` tag, see
+// decodeBytes.
+type bytesValue struct {
+	b    []byte
+	opts valueOptions
+}
 
-func (b *base64StringValue) Set(s string) error {
-	bb, err := base64.StdEncoding.DecodeString(s)
-	if err != nil {
-		return err
+func (b *bytesValue) String() string {
+	if b == nil {
+		return ""
 	}
-	*b = bb
-	return nil
+	return encodeBytes(b.b, b.opts)
 }
 
-type timeSliceValue []time.Time
-
-func (t *timeSliceValue) String() string { return fmt.Sprintf("%v", []time.Time(*t)) }
-
-func (t *timeSliceValue) Set(s string) error {
-	tv, err := time.Parse(time.RFC3339, s)
+func (b *bytesValue) Set(s string) error {
+	bb, err := decodeBytes(s, b.opts)
 	if err != nil {
 		return err
 	}
-	*t = append(*t, tv)
+	b.b = bb
 	return nil
 }
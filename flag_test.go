@@ -1,8 +1,13 @@
 package configurator
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -122,6 +127,192 @@ func TestFlagProvider(t *testing.T) {
 	}, tt)
 }
 
+func TestFlagProvider_Args(t *testing.T) {
+	type example struct {
+		Verbose bool     `config:"flag=v"`
+		Src     string   `config:"arg=0"`
+		Count   int      `config:"arg=1"`
+		Files   []string `config:"args"`
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		expect *example
+		err    error
+	}{
+		{
+			name:   "all positionals",
+			args:   []string{"cmd", "-v", "src", "3", "a.txt", "b.txt"},
+			expect: &example{Verbose: true, Src: "src", Count: 3, Files: []string{"a.txt", "b.txt"}},
+		},
+		{
+			name:   "no rest",
+			args:   []string{"cmd", "src", "3"},
+			expect: &example{Src: "src", Count: 3},
+		},
+		{
+			name: "missing required",
+			args: []string{"cmd", "-v", "src"},
+			err:  ErrMissingArg,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = tt.args
+			resetForTesting()
+			cfg := &example{}
+			si, err := getStructInfo(cfg, nil)
+			assert.NoError(t, err)
+
+			err = NewFlagProvider().Provide(cfg, si)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, cfg)
+		})
+	}
+}
+
+func TestFlagProvider_ArgsTag(t *testing.T) {
+	type example struct {
+		Name string `config:"args"`
+	}
+	_, err := getStructInfo(&example{}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidTagFormat))
+}
+
+func TestFlagProvider_Map(t *testing.T) {
+	type example struct {
+		Labels  map[string]string  `config:"flag=label"`
+		Weights map[string]float64 `config:"flag,sep=;,kvsep=:"`
+	}
+
+	os.Args = []string{"cmd", "-label", "a=1", "-label", "b=2,c=3", "-weights", "x:0.5;y:2"}
+	resetForTesting()
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewFlagProvider().Provide(cfg, si)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{
+		Labels:  map[string]string{"a": "1", "b": "2", "c": "3"},
+		Weights: map[string]float64{"x": 0.5, "y": 2},
+	}, cfg)
+}
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", b)
+	}
+	return nil
+}
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info"}[l]), nil
+}
+
+type upperValue string
+
+func (u *upperValue) String() string { return string(*u) }
+
+func (u *upperValue) Set(s string) error {
+	*u = upperValue(strings.ToUpper(s))
+	return nil
+}
+
+func TestFlagProvider_Text(t *testing.T) {
+	type example struct {
+		Level    logLevel    `config:"flag"`
+		LevelPtr *logLevel   `config:"flag"`
+		IP       net.IP      `config:"flag"`
+		IPs      []net.IP    `config:"flag"`
+		Upper    upperValue  `config:"flag"`
+		Uppers   *upperValue `config:"flag"`
+	}
+
+	os.Args = []string{"cmd", "-level=info", "-levelptr=debug", "-ip=10.0.0.1", "-ips=::1", "-ips=127.0.0.1",
+		"-upper=abc", "-uppers=def"}
+	resetForTesting()
+	cfg := &example{}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = NewFlagProvider().Provide(cfg, si)
+	assert.NoError(t, err)
+
+	debug := logLevel(0)
+	upper := upperValue("DEF")
+	assert.Equal(t, &example{
+		Level:    logLevel(1),
+		LevelPtr: &debug,
+		IP:       net.ParseIP("10.0.0.1"),
+		IPs:      []net.IP{net.ParseIP("::1"), net.ParseIP("127.0.0.1")},
+		Upper:    upperValue("ABC"),
+		Uppers:   &upper,
+	}, cfg)
+	assert.Equal(t, "debug", flag.Lookup("level").DefValue)
+}
+
+func TestFlagProvider_Reload(t *testing.T) {
+	type example struct {
+		Port int      `config:"flag"`
+		Tags []string `config:"flag=tag"`
+		Name string   `config:"arg=0"`
+	}
+
+	os.Args = []string{"cmd", "-verbose", "-port", "8080", "-tag", "a", "-tag", "b", "svc"}
+	resetForTesting()
+	verbose := flag.Bool("verbose", false, "")
+
+	h, err := NewHolder(NewConfigurator(WithFileProvider(""), WithFlagProvider()), &example{})
+	assert.NoError(t, err)
+	assert.True(t, *verbose)
+	assert.NoError(t, h.Reload())
+	assert.Equal(t, &example{Port: 8080, Tags: []string{"a", "b"}, Name: "svc"}, h.Get())
+	assert.Equal(t, uint64(2), h.Version())
+}
+
+func TestFlagProvider_ConcurrentLoads(t *testing.T) {
+	type example struct {
+		Port int `config:"flag"`
+	}
+
+	os.Args = []string{"cmd", "-port", "8080"}
+	resetForTesting()
+	c := NewConfigurator(WithFileProvider(""), WithFlagProvider())
+	h1, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+	h2, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for _, fn := range []func() error{h1.Reload, h2.Reload, func() error { return c.Load(&example{}) }} {
+		wg.Add(1)
+		go func(fn func() error) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				assert.NoError(t, fn())
+			}
+		}(fn)
+	}
+	wg.Wait()
+	assert.Equal(t, &example{Port: 8080}, h1.Get())
+	assert.Equal(t, &example{Port: 8080}, h2.Get())
+}
+
 func resetForTesting() {
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 }
@@ -157,3 +348,50 @@ func s(v string) *string { return &v }
 func tptr(v time.Duration) *time.Duration { return &v }
 
 func timePtr(v time.Time) *time.Time { return &v }
+
+func TestFlagProvider_OutOfRange(t *testing.T) {
+	type example struct {
+		I8     int8     `config:"flag"`
+		I8ptr  *int8    `config:"flag"`
+		U16    uint16   `config:"flag"`
+		F32    float32  `config:"flag"`
+		I32s   []int32  `config:"flag"`
+		U8ptrs []*uint8 `config:"flag"`
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		err  bool
+	}{
+		{name: "in range", args: []string{"-i8=-128", "-i8ptr=127", "-u16=65535", "-f32=1.5", "-i32s=1", "-u8ptrs=255"}},
+		{name: "int8", args: []string{"-i8=300"}, err: true},
+		{name: "int8 pointer", args: []string{"-i8ptr=-129"}, err: true},
+		{name: "uint16", args: []string{"-u16=65536"}, err: true},
+		{name: "float32", args: []string{"-f32=1e39"}, err: true},
+		{name: "int32 slice", args: []string{"-i32s=2147483648"}, err: true},
+		{name: "uint8 pointer slice", args: []string{"-u8ptrs=256"}, err: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = append([]string{"cmd"}, tt.args...)
+			resetForTesting()
+			cfg := &example{}
+			si, err := getStructInfo(cfg, nil)
+			assert.NoError(t, err)
+
+			err = NewFlagProvider().Provide(cfg, si)
+			if tt.err {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), ErrOutOfRange.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int8(-128), cfg.I8)
+			assert.Equal(t, int8(127), *cfg.I8ptr)
+			assert.Equal(t, uint8(255), *cfg.U8ptrs[0])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package configurator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Holder holds the config loaded by a Configurator for concurrent readers.
+// Every load builds a new config, published whole only if it loads and
+// validates, so that Get never returns a config being loaded, and no lock
+// is taken reading it. The configs returned by Get must not be modified.
+type Holder struct {
+	c   *Configurator
+	typ reflect.Type
+
+	// mu serializes the loads, the reads go through current.
+	mu      sync.Mutex
+	current atomic.Value
+}
+
+// holderSnapshot is a config published by a Holder, with its version.
+type holderSnapshot struct {
+	value   interface{}
+	version uint64
+}
+
+// NewHolder returns a Holder of the configs of the type target points to,
+// loaded by c. The first config is loaded at once.
+func NewHolder(c *Configurator, target interface{}) (*Holder, error) {
+	typ, err := c.configType(target)
+	if err != nil {
+		return nil, err
+	}
+	h := &Holder{c: c, typ: typ}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Get returns the last config published, a pointer of the type of the target
+// of NewHolder.
+func (h *Holder) Get() interface{} {
+	return h.snapshot().value
+}
+
+// Version returns the version of the config returned by Get, which goes up
+// by one with every config published, from 1.
+func (h *Holder) Version() uint64 {
+	return h.snapshot().version
+}
+
+// Reload loads a new config and publishes it, keeping the current one if it
+// fails.
+func (h *Holder) Reload() error {
+	h.mu.Lock()
+	v, old, err := h.c.reload(h.typ)
+	if err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	h.publish(v)
+	h.mu.Unlock()
+	h.c.notify(old, v)
+	return nil
+}
+
+// Watch reloads and publishes the config whenever the file of the file
+// provider changes, until ctx is done, see Configurator.Watch. The reloads
+// are serialized with the ones of Reload, so that an older config is never
+// published over a newer one.
+func (h *Holder) Watch(ctx context.Context) error {
+	return h.c.watchFile(ctx, h.Reload)
+}
+
+// publish publishes v as the next version, with h.mu held.
+func (h *Holder) publish(v interface{}) {
+	h.current.Store(holderSnapshot{value: v, version: h.snapshot().version + 1})
+}
+
+func (h *Holder) snapshot() holderSnapshot {
+	s, _ := h.current.Load().(holderSnapshot)
+	return s
+}
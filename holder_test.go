@@ -0,0 +1,115 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHolder(t *testing.T) {
+	type example struct {
+		Level string `config:"env,default=info,oneof=debug|info|warn"`
+		Tags  []string
+	}
+
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider())
+	h, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+	first := h.Get().(*example)
+	assert.Equal(t, &example{Level: "info"}, first)
+	assert.Equal(t, uint64(1), h.Version())
+
+	os.Setenv("APP_LEVEL", "debug")
+	defer os.Unsetenv("APP_LEVEL")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cfg := h.Get().(*example)
+				assert.Contains(t, []string{"info", "debug"}, cfg.Level)
+			}
+		}()
+	}
+	assert.NoError(t, h.Reload())
+	wg.Wait()
+	assert.Equal(t, &example{Level: "debug"}, h.Get())
+	assert.Equal(t, uint64(2), h.Version())
+	assert.Equal(t, &example{Level: "info"}, first)
+
+	os.Setenv("APP_LEVEL", "trace")
+	assert.True(t, errors.Is(h.Reload(), ErrNotAllowed))
+	assert.Equal(t, &example{Level: "debug"}, h.Get())
+	assert.Equal(t, uint64(2), h.Version())
+
+	_, err = NewHolder(c, &example{})
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	_, err = NewHolder(c, example{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+	_, err = NewHolder(c, (*example)(nil))
+	assert.True(t, errors.Is(err, ErrNotAllowed), "a nil target gives the type only")
+}
+
+func TestHolder_Watch(t *testing.T) {
+	type example struct {
+		Level string `yaml:"level"`
+	}
+
+	dir, err := ioutil.TempDir("", "holder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(name, []byte("level: info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConfigurator(WithFileProvider(name), WithWatchInterval(5*time.Millisecond))
+	h, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- h.Watch(ctx) }()
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for ctx.Err() == nil {
+			assert.NoError(t, h.Reload())
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	tmp := name + "~"
+	if err := ioutil.WriteFile(tmp, []byte("level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Get().(*example).Level != "debug" {
+		if time.Now().After(deadline) {
+			t.Fatal("no change published")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	<-reloaded
+
+	v := h.Version()
+	assert.NoError(t, h.Reload())
+	assert.Equal(t, &example{Level: "debug"}, h.Get())
+	assert.Equal(t, v+1, h.Version())
+	assert.Equal(t, "level: file "+name+`:1:8="debug"`, c.Explain("level"))
+}
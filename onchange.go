@@ -0,0 +1,207 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// subscription is a function subscribed to the changes under a path, see
+// OnChange.
+type subscription struct {
+	path string
+	fn   func(old, new interface{})
+	// checked is set once path is found in the type of the configs.
+	checked bool
+}
+
+// covers reports whether a change at path is under the path of s.
+func (s subscription) covers(path string) bool {
+	return s.path == "" || path == s.path ||
+		strings.HasPrefix(path, s.path+".") || strings.HasPrefix(path, s.path+"[")
+}
+
+// OnChange subscribes fn to the changes of the values at path, or under it,
+// made by the reloads of Watch and Holder, see Diff for the paths. The field
+// names of path are case-insensitive, the keys of the elements are not, e.g.
+// `DBs[primary].host`. fn is called once per reload changing them, after the
+// new config is delivered, with the old and the new values at path, nil
+// where there is none, e.g. the pool struct for `database.pool`, a pointer
+// being followed. The values are copies, like the values of Diff, but for an
+// empty path subscribing to the whole configs. A panic of fn is recovered
+// and reported to the handler set by WithWatchErrorHandler, the other
+// subscribers being called anyway.
+//
+// A path which is not in the type of the last config loaded is an
+// ErrUnknownPath. Subscribed before any load, it is checked by the first
+// reload, which reports it to the handler and drops the subscription.
+func (c *Configurator) OnChange(path string, fn func(old, new interface{})) error {
+	s := subscription{path: normalizePath(strings.TrimSpace(path)), fn: fn}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded != nil {
+		if err := c.checkPath(reflect.TypeOf(c.loaded).Elem(), s.path); err != nil {
+			return err
+		}
+		s.checked = true
+	}
+	c.subs = append(c.subs, s)
+	return nil
+}
+
+// checkSubs checks the paths of the subscriptions not checked yet against
+// typ, reporting and dropping the unknown ones, and returns the subscriptions
+// kept.
+func (c *Configurator) checkSubs(typ reflect.Type) []subscription {
+	var errs []error
+	c.mu.Lock()
+	subs := make([]subscription, 0, len(c.subs))
+	for _, s := range c.subs {
+		if !s.checked {
+			if err := c.checkPath(typ, s.path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.checked = true
+		}
+		subs = append(subs, s)
+	}
+	c.subs = subs
+	c.mu.Unlock()
+
+	if c.watchErrors != nil {
+		for _, err := range errs {
+			c.watchErrors(err)
+		}
+	}
+	return subs
+}
+
+// checkPath checks that path is the path of a field of typ, or of a value
+// holding fields, e.g. `database.pool` or `listeners[0]`.
+func (c *Configurator) checkPath(typ reflect.Type, path string) error {
+	if path == "" {
+		return nil
+	}
+	si, err := walkStruct(reflect.New(typ).Interface(), nil, c.conv, nil)
+	if err != nil {
+		return err
+	}
+	if !hasPath(si.Fields(), pathTemplate(path)) {
+		return fmt.Errorf("Configurator/OnChange: %w [%s]", ErrUnknownPath, path)
+	}
+	return nil
+}
+
+// hasPath reports whether tmpl is the path of one of fields, of the fields of
+// their elements, or of their lineages.
+func hasPath(fields []FieldInfo, tmpl string) bool {
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		for _, p := range f.lineage() {
+			if p.Path() == tmpl {
+				return true
+			}
+		}
+		if e := f.Elem(); e != nil && hasPath(e.Fields(), tmpl) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePath lowercases the field names of path, like FieldInfo.Path,
+// keeping the keys of the elements, e.g. `dbs[Primary].host` for
+// `DBs[Primary].Host`.
+func normalizePath(path string) string {
+	var b strings.Builder
+	inKey := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			inKey = true
+		case r == ']':
+			inKey = false
+		case !inKey:
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pathTemplate returns path without the keys of its elements, like the paths
+// of the fields of an element template, e.g. `dbs[].host`.
+func pathTemplate(path string) string {
+	var b strings.Builder
+	inKey := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			inKey = true
+		case r == ']':
+			inKey = false
+		case inKey:
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// notify calls the subscribers to the values changed between the configs
+// old and new, if old is not nil, once their paths are checked against the
+// type of new.
+func (c *Configurator) notify(old, new interface{}) {
+	subs := c.checkSubs(reflect.TypeOf(new).Elem())
+	if old == nil || len(subs) == 0 {
+		return
+	}
+	changes := diff(old, new, c.conv)
+	if len(changes) == 0 {
+		return
+	}
+	_, oldNodes, err := diffLeaves(reflect.ValueOf(old), c.conv)
+	if err != nil {
+		return
+	}
+	_, newNodes, err := diffLeaves(reflect.ValueOf(new), c.conv)
+	if err != nil {
+		return
+	}
+
+	for _, s := range subs {
+		for _, ch := range changes {
+			if s.covers(ch.Path) {
+				c.callSubscriber(s, nodeValue(oldNodes, s.path, old), nodeValue(newNodes, s.path, new))
+				break
+			}
+		}
+	}
+}
+
+// callSubscriber calls s, recovering from its panic.
+func (c *Configurator) callSubscriber(s subscription, old, new interface{}) {
+	defer func() {
+		if r := recover(); r != nil && c.watchErrors != nil {
+			c.watchErrors(fmt.Errorf("Configurator/OnChange: subscriber of [%s] panicked: %v", s.path, r))
+		}
+	}()
+	s.fn(old, new)
+}
+
+// nodeValue returns the value at path in nodes, or the config v itself for
+// an empty path.
+func nodeValue(nodes map[string]reflect.Value, path string, v interface{}) interface{} {
+	if path == "" {
+		return v
+	}
+	if n, ok := nodes[path]; ok && n.IsValid() {
+		return n.Interface()
+	}
+	return nil
+}
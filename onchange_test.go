@@ -0,0 +1,122 @@
+package configurator
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnChange(t *testing.T) {
+	type pool struct {
+		Size int `config:"env"`
+	}
+	type database struct {
+		Host string `config:"env"`
+		Pool *pool
+	}
+	type example struct {
+		Level    string `config:"env"`
+		Database database
+		Backends map[string]pool `config:"env"`
+	}
+
+	os.Setenv("APP_LEVEL", "info")
+	os.Setenv("APP_DATABASE_POOL_SIZE", "10")
+	defer os.Unsetenv("APP_LEVEL")
+	defer os.Unsetenv("APP_DATABASE_POOL_SIZE")
+
+	var errs []error
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithWatchErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	type call struct {
+		old, new interface{}
+	}
+	calls := make(map[string][]call)
+	for _, path := range []string{"database.pool", "Database", "level", "database.host", "Backends[a]", "", "database.port"} {
+		path := path
+		assert.NoError(t, c.OnChange(path, func(old, new interface{}) {
+			calls[path] = append(calls[path], call{old, new})
+		}))
+	}
+	assert.NoError(t, c.OnChange("level", func(old, new interface{}) {
+		panic("bad subscriber")
+	}))
+
+	h, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+	assert.Empty(t, calls, "the first load changes nothing")
+	first := h.Get()
+	if assert.Len(t, errs, 1) {
+		assert.True(t, errors.Is(errs[0], ErrUnknownPath))
+		assert.Contains(t, errs[0].Error(), "[database.port]")
+	}
+	errs = nil
+
+	err = c.OnChange("backends[a].port", func(old, new interface{}) {})
+	assert.True(t, errors.Is(err, ErrUnknownPath))
+	assert.NoError(t, c.OnChange("backends[A].size", func(old, new interface{}) {
+		calls["backends[A].size"] = append(calls["backends[A].size"], call{old, new})
+	}))
+
+	os.Setenv("APP_DATABASE_POOL_SIZE", "20")
+	os.Setenv("APP_BACKENDS_A_SIZE", "1")
+	defer os.Unsetenv("APP_BACKENDS_A_SIZE")
+	assert.NoError(t, h.Reload())
+	assert.Equal(t, map[string][]call{
+		"database.pool": {{pool{Size: 10}, pool{Size: 20}}},
+		"Database": {{
+			database{Pool: &pool{Size: 10}},
+			database{Pool: &pool{Size: 20}},
+		}},
+		"Backends[a]": {{nil, pool{Size: 1}}},
+		"":            {{first, h.Get()}},
+	}, calls)
+
+	os.Setenv("APP_LEVEL", "debug")
+	calls = make(map[string][]call)
+	assert.NoError(t, h.Reload())
+	assert.Equal(t, []call{{"info", "debug"}}, calls["level"], "a panic is isolated")
+	assert.Len(t, calls, 2)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "subscriber of [level] panicked: bad subscriber")
+	}
+
+	calls = make(map[string][]call)
+	assert.NoError(t, h.Reload())
+	assert.Empty(t, calls)
+
+	os.Setenv("APP_DATABASE_POOL_SIZE", "x")
+	assert.Error(t, h.Reload())
+	assert.Empty(t, calls)
+}
+
+type testOpaque struct {
+	v string
+}
+
+func TestOnChange_Converter(t *testing.T) {
+	type example struct {
+		O testOpaque `config:"env"`
+	}
+
+	os.Setenv("APP_O", "a")
+	defer os.Unsetenv("APP_O")
+	c := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"))
+	c.RegisterConverter(reflect.TypeOf(testOpaque{}), func(s string) (interface{}, error) {
+		return testOpaque{v: s}, nil
+	})
+	var calls [][2]interface{}
+	assert.NoError(t, c.OnChange("o", func(old, new interface{}) {
+		calls = append(calls, [2]interface{}{old, new})
+	}))
+
+	h, err := NewHolder(c, &example{})
+	assert.NoError(t, err)
+	os.Setenv("APP_O", "b")
+	assert.NoError(t, h.Reload())
+	assert.Equal(t, [][2]interface{}{{testOpaque{v: "a"}, testOpaque{v: "b"}}}, calls)
+}
@@ -0,0 +1,107 @@
+package configurator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Origin is where the value of a field comes from.
+type Origin struct {
+	// Path is the path of the field, see FieldInfo.Path.
+	Path string
+	// Source is the provider of the value, one of the Source constants.
+	Source string
+	// Key is the environment variable, the flag, the index of the positional
+	// argument or the position in the file the value comes from.
+	Key      string
+	RawValue string
+}
+
+// String formats the origin like `env APP_PORT="8080"` or `default="80"`.
+func (o Origin) String() string {
+	if o.Key == "" {
+		return fmt.Sprintf("%s=%q", o.Source, o.RawValue)
+	}
+	return fmt.Sprintf("%s %s=%q", o.Source, o.Key, o.RawValue)
+}
+
+// origins records the origins of the values set by the providers of a Load,
+// by path in the order they are set. A nil *origins records nothing.
+type origins struct {
+	byPath map[string][]Origin
+}
+
+func newOrigins() *origins {
+	return &origins{byPath: make(map[string][]Origin)}
+}
+
+func (o *origins) record(origin Origin) {
+	if o == nil {
+		return
+	}
+	o.byPath[origin.Path] = append(o.byPath[origin.Path], origin)
+}
+
+// has reports whether a value was set at path.
+func (o *origins) has(path string) bool {
+	return o != nil && len(o.byPath[path]) > 0
+}
+
+// hasElements reports whether a value was set in an element of the
+// collection at path, e.g. at `backends[0].host` for `backends`.
+func (o *origins) hasElements(path string) bool {
+	if o == nil {
+		return false
+	}
+	for p, l := range o.byPath {
+		if len(l) > 0 && strings.HasPrefix(p, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// last returns the origin of the value kept for every path.
+func (o *origins) last() map[string]Origin {
+	m := make(map[string]Origin)
+	if o == nil {
+		return m
+	}
+	for path, l := range o.byPath {
+		m[path] = l[len(l)-1]
+	}
+	return m
+}
+
+// explain describes the origins of the value at path, the last one first,
+// e.g. `port: env APP_PORT="8080", overriding file config.yaml:3:7="80"`.
+func (o *origins) explain(path string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	var l []Origin
+	if o != nil {
+		l = o.byPath[path]
+	}
+	if len(l) == 0 {
+		return fmt.Sprintf("%s: not set by any provider", path)
+	}
+
+	var b strings.Builder
+	b.WriteString(path + ": " + l[len(l)-1].String())
+	for i := len(l) - 2; i >= 0; i-- {
+		if i == len(l)-2 {
+			b.WriteString(", overriding ")
+		} else {
+			b.WriteString(", then ")
+		}
+		b.WriteString(l[i].String())
+	}
+	return b.String()
+}
+
+// originsOf returns the recorder of the fields of si, if any.
+func originsOf(si StructInfo) *origins {
+	if s, ok := si.(*structInfo); ok {
+		return s.origins
+	}
+	return nil
+}
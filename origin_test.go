@@ -0,0 +1,76 @@
+package configurator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrigins(t *testing.T) {
+	type database struct {
+		Host string `yaml:"host" config:"env,flag"`
+		Port int    `yaml:"port" config:"default=5432"`
+	}
+	type backend struct {
+		URL string `yaml:"url" config:"env"`
+	}
+	type example struct {
+		Database database           `yaml:"database"`
+		Backends map[string]backend `yaml:"backends" config:"env"`
+		Timeout  time.Duration      `yaml:"timeout"`
+		Tags     []string           `yaml:"tags" config:"flag"`
+		Target   string             `config:"arg=0"`
+		Unset    string
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`database:
+  host: localhost
+timeout: 1d
+backends:
+  a:
+    url: http://a
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_DATABASE_HOST", "db.internal")
+	os.Setenv("APP_BACKENDS_B_URL", "http://b")
+	defer os.Unsetenv("APP_DATABASE_HOST")
+	defer os.Unsetenv("APP_BACKENDS_B_URL")
+	os.Args = []string{"cmd", "-database-host=db.cli", "-tags=x", "-tags=y", "prod"}
+	resetForTesting()
+
+	c := NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP"), WithFlagProvider(), WithDefaultProvider())
+	assert.Empty(t, c.Origins())
+	cfg := &example{}
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, 24*time.Hour, cfg.Timeout)
+
+	loc := func(line, column int) string {
+		return fmt.Sprintf("%s:%d:%d", f.Name(), line, column)
+	}
+	assert.Equal(t, map[string]Origin{
+		"database.host":   {Path: "database.host", Source: SourceFlag, Key: "database-host", RawValue: "db.cli"},
+		"database.port":   {Path: "database.port", Source: SourceDefault, RawValue: "5432"},
+		"timeout":         {Path: "timeout", Source: SourceFile, Key: loc(3, 10), RawValue: "1d"},
+		"backends[a].url": {Path: "backends[a].url", Source: SourceFile, Key: loc(6, 10), RawValue: "http://a"},
+		"backends[b].url": {Path: "backends[b].url", Source: SourceEnv, Key: "APP_BACKENDS_B_URL", RawValue: "http://b"},
+		"tags":            {Path: "tags", Source: SourceFlag, Key: "tags", RawValue: "x y"},
+		"target":          {Path: "target", Source: SourceArg, Key: "0", RawValue: "prod"},
+	}, c.Origins())
+
+	assert.Equal(t, fmt.Sprintf(`database.host: flag database-host="db.cli", overriding env APP_DATABASE_HOST="db.internal", then file %s="localhost"`, loc(2, 9)),
+		c.Explain("Database.Host"))
+	assert.Equal(t, `database.port: default="5432"`, c.Explain("database.port"))
+	assert.Equal(t, "unset: not set by any provider", c.Explain("unset"))
+}
@@ -1,11 +1,18 @@
 package configurator
 
 import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type StructInfo interface {
@@ -13,7 +20,8 @@ type StructInfo interface {
 }
 
 type structInfo struct {
-	fields []FieldInfo
+	fields  []FieldInfo
+	origins *origins
 }
 
 var _ StructInfo = &structInfo{}
@@ -29,7 +37,18 @@ type FieldInfo interface {
 	Name() string
 	ENVKey() string
 	FlagKey() string
+	ArgIndex() int
+	RestArgs() bool
 	DefVal() string
+	Path() string
+	Elem() StructInfo
+	Keys() []string
+	Element(key string, fn func(StructInfo) error) error
+	options() valueOptions
+	// setOrigin records that source set the field from raw under key.
+	setOrigin(source, key, raw string)
+	// isSet reports whether a provider set the field in this Load.
+	isSet() bool
 }
 
 type fieldInfo struct {
@@ -37,6 +56,14 @@ type fieldInfo struct {
 	field  reflect.StructField
 	val    reflect.Value
 	tag    tagInfo
+	// isElem marks the element of a slice or map of structs, key is the index
+	// or the map key of the element.
+	isElem bool
+	key    string
+	elem   *structInfo
+	conv   *converters
+	// origins records the sources of the values, see Configurator.Origins.
+	origins *origins
 }
 
 var _ FieldInfo = &fieldInfo{}
@@ -57,14 +84,142 @@ func (f *fieldInfo) Name() string {
 	return f.field.Name
 }
 
+// path returns the names of the field and its parents up to the enclosing
+// element of a collection, if any.
 func (f *fieldInfo) path() []string {
 	path := []string{f.Name()}
-	for p := f.parent; p != nil; p = p.parent {
+	for p := f.parent; p != nil && !p.isElem; p = p.parent {
 		path = append([]string{p.Name()}, path...)
 	}
 	return path
 }
 
+// Path returns the full path of the field, e.g. `listeners[0].port` or
+// `dbs[primary].host`. Fields of an element template have an empty key,
+// e.g. `listeners[].port`.
+func (f *fieldInfo) Path() string {
+	var b strings.Builder
+	for _, p := range f.lineage() {
+		if p.isElem {
+			b.WriteString("[" + p.key + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(strings.ToLower(p.Name()))
+	}
+	return b.String()
+}
+
+func (f *fieldInfo) lineage() []*fieldInfo {
+	var l []*fieldInfo
+	for p := f; p != nil; p = p.parent {
+		l = append([]*fieldInfo{p}, l...)
+	}
+	return l
+}
+
+// Elem returns the fields of the element type of a slice or map of structs,
+// or nil for any other field.
+func (f *fieldInfo) Elem() StructInfo {
+	if f.elem == nil {
+		return nil
+	}
+	return f.elem
+}
+
+// Keys returns the indexes or the map keys of the existing elements of a slice
+// or map of structs.
+func (f *fieldInfo) Keys() []string {
+	if f.elem == nil {
+		return nil
+	}
+	switch f.val.Kind() {
+	case reflect.Slice:
+		keys := make([]string, f.val.Len())
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys
+	case reflect.Map:
+		keys := make([]string, 0, f.val.Len())
+		for _, k := range f.val.MapKeys() {
+			keys = append(keys, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	return nil
+}
+
+// Element calls fn with the fields of the element at key of a slice or map of
+// structs, creating the element if it does not exist. A slice grows to hold
+// the index, and a map element is stored back after fn returns.
+func (f *fieldInfo) Element(key string, fn func(StructInfo) error) error {
+	if f.elem == nil {
+		return fmt.Errorf("fieldInfo/Element: %w type [%s]", ErrUnsupported, f.field.Type.String())
+	}
+	root := &fieldInfo{
+		parent:  f,
+		field:   f.field,
+		tag:     f.tag,
+		isElem:  true,
+		key:     key,
+		conv:    f.conv,
+		origins: f.origins,
+	}
+
+	switch f.val.Kind() {
+	case reflect.Slice:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 {
+			return fmt.Errorf("fieldInfo/Element: invalid index [%s] of %s", key, f.Path())
+		}
+		if idx >= f.val.Len() {
+			s := reflect.MakeSlice(f.val.Type(), idx+1, idx+1)
+			reflect.Copy(s, f.val)
+			f.val.Set(s)
+		}
+		root.val = f.val.Index(idx)
+		return f.callElement(root, fn)
+	case reflect.Map:
+		typ := f.val.Type()
+		mk := reflect.New(typ.Key()).Elem()
+		if err := setFieldValue(mk, typ.Key(), key, f.options().elem()); err != nil {
+			return fmt.Errorf("fieldInfo/Element: invalid key [%s] of %s: %w", key, f.Path(), err)
+		}
+		if f.val.IsNil() {
+			f.val.Set(reflect.MakeMap(typ))
+		}
+		root.val = reflect.New(typ.Elem()).Elem()
+		if old := f.val.MapIndex(mk); old.IsValid() {
+			root.val.Set(old)
+		}
+		if err := f.callElement(root, fn); err != nil {
+			return err
+		}
+		f.val.SetMapIndex(mk, root.val)
+		return nil
+	}
+	return fmt.Errorf("fieldInfo/Element: %w type [%s]", ErrUnsupported, f.field.Type.String())
+}
+
+func (f *fieldInfo) callElement(root *fieldInfo, fn func(StructInfo) error) error {
+	e := root.val
+	if e.Kind() == reflect.Ptr {
+		if e.IsNil() {
+			e.Set(reflect.New(e.Type().Elem()))
+		}
+		e = e.Elem()
+	}
+	si, err := getStructInfo(e.Addr().Interface(), root)
+	if err != nil {
+		return err
+	}
+	return fn(si)
+}
+
 func (f *fieldInfo) ENVKey() string {
 	if f.tag.hasENV {
 		if f.tag.env == "" {
@@ -85,6 +240,20 @@ func (f *fieldInfo) FlagKey() string {
 	return ""
 }
 
+// ArgIndex returns the position of the positional argument bound to the field,
+// or -1 if the field is not bound to one.
+func (f *fieldInfo) ArgIndex() int {
+	if f.tag.hasArg {
+		return f.tag.arg
+	}
+	return -1
+}
+
+// RestArgs reports whether the field collects the remaining positional arguments.
+func (f *fieldInfo) RestArgs() bool {
+	return f.tag.hasArgs
+}
+
 func (f *fieldInfo) DefVal() string {
 	if f.tag.hasDefault {
 		return f.tag.defVal
@@ -92,12 +261,131 @@ func (f *fieldInfo) DefVal() string {
 	return ""
 }
 
+func (f *fieldInfo) options() valueOptions {
+	return valueOptions{
+		sep:      f.tag.sep,
+		kvSep:    f.tag.kvSep,
+		layout:   f.tag.layout,
+		enum:     f.tag.enum,
+		encoding: f.tag.encoding,
+		length:   f.tag.length,
+		hasLen:   f.tag.hasLen,
+		conv:     f.conv,
+	}
+}
+
+func (f *fieldInfo) setOrigin(source, key, raw string) {
+	if f.isSecret() && raw != "" {
+		raw = secretMask
+	}
+	f.origins.record(Origin{Path: f.Path(), Source: source, Key: key, RawValue: raw})
+}
+
+func (f *fieldInfo) isSet() bool {
+	return f.origins.has(f.Path())
+}
+
+// valueOptions controls how a raw string is converted into a field value.
+type valueOptions struct {
+	// sep separates the elements of a slice or the entries of a map.
+	sep string
+	// kvSep separates the key and the value of a map entry.
+	kvSep string
+	// layout is the layout of times, see parseTime.
+	layout string
+	// enum holds the allowed values, matched case-insensitively.
+	enum []string
+	// encoding is the encoding of a []byte, see decodeBytes.
+	encoding string
+	// length is the length a []byte must have if hasLen.
+	length int
+	hasLen bool
+	// conv holds the converters registered on the Configurator.
+	conv *converters
+}
+
+// elem returns the options for the elements of a slice or a map.
+func (o valueOptions) elem() valueOptions {
+	return valueOptions{layout: o.layout, enum: o.enum, encoding: o.encoding, conv: o.conv}
+}
+
+func (o valueOptions) converter(typ reflect.Type) (Converter, bool) {
+	return findConverter(o.conv, typ)
+}
+
+func (o valueOptions) separator() string {
+	if o.sep == "" {
+		return ","
+	}
+	return o.sep
+}
+
+func (o valueOptions) kvSeparator() string {
+	if o.kvSep == "" {
+		return "="
+	}
+	return o.kvSep
+}
+
 var (
 	timePtrType = reflect.TypeOf((*time.Time)(nil))
 	timeType    = reflect.TypeOf(time.Time{})
 )
 
+// hasElemAncestor reports whether an enclosing element of f has the struct
+// type typ, which stops recursive types from being walked endlessly.
+func hasElemAncestor(f *fieldInfo, typ reflect.Type) bool {
+	for p := f; p != nil; p = p.parent {
+		if p.isElem && structElemType(p.field.Type, p.conv) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// structElemType returns the struct type of the elements of a slice or a map
+// of structs or struct pointers, or nil for any other type.
+func structElemType(typ reflect.Type, conv *converters) reflect.Type {
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Map {
+		return nil
+	}
+	if isLeafType(typ.Elem(), conv) {
+		return nil
+	}
+	et := typ.Elem()
+	if et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return nil
+	}
+	return et
+}
+
+// isLeafType reports whether typ is set from a single value rather than
+// walked into, like the time types and the types having a converter or
+// implementing encoding.TextUnmarshaler or flag.Value.
+func isLeafType(typ reflect.Type, conv *converters) bool {
+	if typ == timeType || typ == timePtrType || isTextType(typ) {
+		return true
+	}
+	_, ok := findConverter(conv, typ)
+	return ok
+}
+
 func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
+	var conv *converters
+	var o *origins
+	if parent != nil {
+		conv, o = parent.conv, parent.origins
+	}
+	return walkStruct(i, parent, conv, o)
+}
+
+// walkStruct collects the fields of the struct i points to, using the
+// converters conv to tell the leaf types. The fields record the sources of
+// their values into o, if not nil.
+func walkStruct(i interface{}, parent *fieldInfo, conv *converters, o *origins) (*structInfo, error) {
 	v := reflect.ValueOf(i)
 	for v.Kind() != reflect.Ptr {
 		return nil, ErrInvalidConfig
@@ -107,7 +395,7 @@ func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
 		return nil, ErrInvalidConfig
 	}
 
-	si := &structInfo{}
+	si := &structInfo{origins: o}
 	typ := v.Type()
 	if v.Kind() == reflect.Struct {
 		n := v.NumField()
@@ -120,8 +408,8 @@ func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
 				continue
 			}
 
-			if ft.Type == timeType || ft.Type == timePtrType {
-				fi, err := getFieldInfo(fv, ft, parent)
+			if isLeafType(ft.Type, conv) {
+				fi, err := getFieldInfo(fv, ft, parent, conv, o)
 				if err != nil {
 					return nil, err
 				}
@@ -141,7 +429,7 @@ func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
 				fv = fv.Elem()
 			}
 
-			fi, err := getFieldInfo(fv, ft, parent)
+			fi, err := getFieldInfo(fv, ft, parent, conv, o)
 			if err != nil {
 				return nil, err
 			}
@@ -152,7 +440,7 @@ func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
 				if ft.Anonymous {
 					p = parent
 				}
-				inner, err := getStructInfo(fv.Addr().Interface(), p)
+				inner, err := walkStruct(fv.Addr().Interface(), p, conv, o)
 				if err != nil {
 					return nil, err
 				}
@@ -160,17 +448,29 @@ func getStructInfo(i interface{}, parent *fieldInfo) (*structInfo, error) {
 				continue
 			}
 
+			if et := structElemType(ft.Type, conv); et != nil && !hasElemAncestor(parent, et) {
+				root := &fieldInfo{parent: fi, field: ft, tag: fi.tag, isElem: true, conv: conv, origins: o}
+				root.val = reflect.New(et).Elem()
+				elem, err := walkStruct(root.val.Addr().Interface(), root, conv, o)
+				if err != nil {
+					return nil, err
+				}
+				fi.elem = elem
+			}
+
 			si.fields = append(si.fields, fi)
 		}
 	}
 	return si, nil
 }
 
-func getFieldInfo(v reflect.Value, t reflect.StructField, p *fieldInfo) (*fieldInfo, error) {
+func getFieldInfo(v reflect.Value, t reflect.StructField, p *fieldInfo, conv *converters, o *origins) (*fieldInfo, error) {
 	fi := &fieldInfo{
-		field:  t,
-		val:    v,
-		parent: p,
+		field:   t,
+		val:     v,
+		parent:  p,
+		conv:    conv,
+		origins: o,
 	}
 
 	tag, err := parseTag(t)
@@ -182,14 +482,30 @@ func getFieldInfo(v reflect.Value, t reflect.StructField, p *fieldInfo) (*fieldI
 }
 
 const (
-	tagName              = "config"
-	tagSeparator         = ","
-	flagFlag             = "flag"
-	flagFlagWithValue    = "flag="
-	envFlag              = "env"
-	envFlagWithValue     = "env="
-	defaultFlag          = "default"
-	defaultFlagWithValue = "default="
+	tagName               = "config"
+	tagSeparator          = ","
+	flagFlag              = "flag"
+	flagFlagWithValue     = "flag="
+	envFlag               = "env"
+	envFlagWithValue      = "env="
+	defaultFlag           = "default"
+	defaultFlagWithValue  = "default="
+	argFlag               = "arg"
+	argFlagWithValue      = "arg="
+	argsFlag              = "args"
+	sepFlagWithValue      = "sep="
+	kvSepFlagWithValue    = "kvsep="
+	layoutFlagWithValue   = "layout="
+	enumFlagWithValue     = "enum="
+	oneofFlagWithValue    = "oneof="
+	enumSeparator         = "|"
+	encodingFlagWithValue = "encoding="
+	lenFlagWithValue      = "len="
+	requiredFlag          = "required"
+	minFlagWithValue      = "min="
+	maxFlagWithValue      = "max="
+	patternFlagWithValue  = "pattern="
+	secretFlag            = "secret"
 )
 
 type tagInfo struct {
@@ -199,13 +515,28 @@ type tagInfo struct {
 	hasENV     bool
 	defVal     string
 	hasDefault bool
+	arg        int
+	hasArg     bool
+	hasArgs    bool
+	sep        string
+	kvSep      string
+	layout     string
+	enum       []string
+	encoding   string
+	length     int
+	hasLen     bool
+	required   bool
+	min        string
+	max        string
+	pattern    *regexp.Regexp
+	secret     bool
 }
 
 func parseTag(field reflect.StructField) (*tagInfo, error) {
 	t := tagInfo{}
 	val := field.Tag.Get(tagName)
 	tags := strings.Split(val, tagSeparator)
-	for _, s := range tags {
+	for i, s := range tags {
 		switch {
 		case strings.HasPrefix(s, envFlag):
 			if err := parseENV(field, &t, s); err != nil {
@@ -219,6 +550,68 @@ func parseTag(field reflect.StructField) (*tagInfo, error) {
 			if err := parseDefault(field, &t, s); err != nil {
 				return nil, err
 			}
+		case strings.HasPrefix(s, sepFlagWithValue):
+			t.sep = strings.TrimPrefix(s, sepFlagWithValue)
+			if t.sep == "" {
+				return nil, fmt.Errorf("%w, `sep=separator` requires a separator", ErrInvalidTagFormat)
+			}
+		case strings.HasPrefix(s, kvSepFlagWithValue):
+			t.kvSep = strings.TrimPrefix(s, kvSepFlagWithValue)
+			if t.kvSep == "" {
+				return nil, fmt.Errorf("%w, `kvsep=separator` requires a separator", ErrInvalidTagFormat)
+			}
+		case strings.HasPrefix(s, enumFlagWithValue), strings.HasPrefix(s, oneofFlagWithValue):
+			if err := parseEnum(field, &t, s); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(s, encodingFlagWithValue):
+			if err := parseEncoding(field, &t, s); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(s, lenFlagWithValue):
+			i, err := strconv.Atoi(strings.TrimPrefix(s, lenFlagWithValue))
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("%w, `len=length` requires a non-negative length", ErrInvalidTagFormat)
+			}
+			t.length, t.hasLen = i, true
+		case s == requiredFlag:
+			t.required = true
+		case s == secretFlag:
+			t.secret = true
+		case strings.HasPrefix(s, minFlagWithValue):
+			t.min = strings.TrimPrefix(s, minFlagWithValue)
+			if t.min == "" {
+				return nil, fmt.Errorf("%w, `min=value` requires a value", ErrInvalidTagFormat)
+			}
+		case strings.HasPrefix(s, maxFlagWithValue):
+			t.max = strings.TrimPrefix(s, maxFlagWithValue)
+			if t.max == "" {
+				return nil, fmt.Errorf("%w, `max=value` requires a value", ErrInvalidTagFormat)
+			}
+		case strings.HasPrefix(s, patternFlagWithValue):
+			if err := parsePattern(field, &t, s); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(s, layoutFlagWithValue):
+			t.layout = strings.TrimPrefix(s, layoutFlagWithValue)
+			if t.layout == "" {
+				return nil, fmt.Errorf("%w, `layout=layout` requires a layout", ErrInvalidTagFormat)
+			}
+		case s == argsFlag:
+			if err := parseArgs(field, &t, s); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(s, argFlag):
+			if err := parseArg(field, &t, s); err != nil {
+				return nil, err
+			}
+		case s == "":
+		default:
+			if i > 0 && strings.HasPrefix(tags[i-1], defaultFlag) {
+				return nil, fmt.Errorf("%w, unknown option [%s] of field [%s]: a default list needs a separator other than `,`, e.g. `default=a;b,sep=;`",
+					ErrInvalidTagFormat, s, field.Name)
+			}
+			return nil, fmt.Errorf("%w, unknown option [%s] of field [%s]", ErrInvalidTagFormat, s, field.Name)
 		}
 	}
 
@@ -258,7 +651,152 @@ func parseDefault(field reflect.StructField, t *tagInfo, v string) error {
 	return nil
 }
 
-func setFieldValue(val reflect.Value, typ reflect.Type, v string) error {
+func parseArg(field reflect.StructField, t *tagInfo, v string) error {
+	if !strings.HasPrefix(v, argFlagWithValue) {
+		return fmt.Errorf("%w, `arg=index` is valid", ErrInvalidTagFormat)
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(v, argFlagWithValue))
+	if err != nil || i < 0 {
+		return fmt.Errorf("%w, `arg=index` requires a non-negative index", ErrInvalidTagFormat)
+	}
+	t.hasArg = true
+	t.arg = i
+	return nil
+}
+
+func parseEnum(field reflect.StructField, t *tagInfo, v string) error {
+	v = strings.TrimPrefix(strings.TrimPrefix(v, enumFlagWithValue), oneofFlagWithValue)
+	for _, e := range strings.Split(v, enumSeparator) {
+		if e == "" {
+			return fmt.Errorf("%w, `enum=a|b|c` requires non-empty values", ErrInvalidTagFormat)
+		}
+		t.enum = append(t.enum, e)
+	}
+	return nil
+}
+
+func parseEncoding(field reflect.StructField, t *tagInfo, v string) error {
+	t.encoding = strings.TrimPrefix(v, encodingFlagWithValue)
+	switch t.encoding {
+	case encodingHex, encodingBase64, encodingBase64URL, encodingRaw:
+		return nil
+	}
+	return fmt.Errorf("%w, `encoding` is one of hex, base64, base64url or raw", ErrInvalidTagFormat)
+}
+
+// parsePattern compiles the regular expression of `pattern=regexp`, which
+// cannot hold a comma.
+func parsePattern(field reflect.StructField, t *tagInfo, v string) error {
+	expr := strings.TrimPrefix(v, patternFlagWithValue)
+	if expr == "" {
+		return fmt.Errorf("%w, `pattern=regexp` requires a regular expression", ErrInvalidTagFormat)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("%w, `pattern=regexp` requires a valid regular expression: %v", ErrInvalidTagFormat, err)
+	}
+	t.pattern = re
+	return nil
+}
+
+func parseArgs(field reflect.StructField, t *tagInfo, v string) error {
+	if field.Type.Kind() != reflect.Slice {
+		return fmt.Errorf("%w, `args` requires a slice field", ErrInvalidTagFormat)
+	}
+	t.hasArgs = true
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+)
+
+// isTextType reports whether typ, or a pointer to it, implements
+// encoding.TextUnmarshaler or flag.Value. The time types are left to the
+// conversion of setFieldValue.
+func isTextType(typ reflect.Type) bool {
+	if typ == timeType || typ == timePtrType {
+		return false
+	}
+	for _, t := range []reflect.Type{typ, reflect.PtrTo(typ)} {
+		if t.Implements(textUnmarshalerType) || t.Implements(flagValueType) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTextValue sets val through the encoding.TextUnmarshaler or flag.Value
+// implemented by typ or a pointer to it. A pointer field gets a new value.
+func setTextValue(val reflect.Value, typ reflect.Type, v string) error {
+	var p reflect.Value
+	switch {
+	case typ.Kind() == reflect.Ptr && (typ.Implements(textUnmarshalerType) || typ.Implements(flagValueType)):
+		p = reflect.New(typ.Elem())
+	case val.CanAddr():
+		p = val.Addr()
+	default:
+		return fmt.Errorf("setTextValue: %w type [%s]", ErrUnsupported, typ.String())
+	}
+
+	var err error
+	switch u := p.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		err = u.UnmarshalText([]byte(v))
+	case flag.Value:
+		err = u.Set(v)
+	default:
+		return fmt.Errorf("setTextValue: %w type [%s]", ErrUnsupported, typ.String())
+	}
+	if err != nil {
+		return err
+	}
+	if p.Type() == typ {
+		val.Set(p)
+	}
+	return nil
+}
+
+// textString formats val through the encoding.TextMarshaler or flag.Value
+// implemented by its type or a pointer to it.
+func textString(val reflect.Value) string {
+	if !val.IsValid() {
+		return ""
+	}
+	candidates := []reflect.Value{val}
+	if val.CanAddr() {
+		candidates = append(candidates, val.Addr())
+	}
+	for _, c := range candidates {
+		if c.Kind() == reflect.Ptr && c.IsNil() {
+			return ""
+		}
+		if m, ok := c.Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			if err != nil {
+				return ""
+			}
+			return string(b)
+		}
+		if fv, ok := c.Interface().(flag.Value); ok {
+			return fv.String()
+		}
+	}
+	return fmt.Sprint(val.Interface())
+}
+
+func setFieldValue(val reflect.Value, typ reflect.Type, v string, opts valueOptions) error {
+	if len(opts.enum) > 0 && isScalarType(typ, opts) {
+		return setEnumValue(val, typ, v, opts)
+	}
+	if fn, ok := opts.converter(typ); ok {
+		return setConvertedValue(val, typ, v, fn)
+	}
+	if isTextType(typ) {
+		return setTextValue(val, typ, v)
+	}
 	switch typ.Kind() {
 	case reflect.Bool:
 		b, err := strconv.ParseBool(v)
@@ -266,57 +804,48 @@ func setFieldValue(val reflect.Value, typ reflect.Type, v string) error {
 			return err
 		}
 		val.SetBool(b)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
-		i, err := strconv.ParseInt(v, 0, strconv.IntSize)
-		if err != nil {
-			return err
-		}
-		val.SetInt(i)
-	case reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if typ == durationType {
-			i, err := time.ParseDuration(v)
+			i, err := parseDuration(v)
 			if err != nil {
 				return err
 			}
 			val.SetInt(int64(i))
-		} else {
-			i, err := strconv.ParseInt(v, 0, 64)
-			if err != nil {
-				return err
-			}
-			val.SetInt(i)
+			return nil
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
-		u, err := strconv.ParseUint(v, 0, strconv.IntSize)
+		i, err := strconv.ParseInt(v, 0, typ.Bits())
 		if err != nil {
-			return err
+			return numError(v, typ, err)
 		}
-		val.SetUint(u)
-	case reflect.Uint64:
-		u, err := strconv.ParseUint(v, 0, 64)
+		val.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(v, 0, typ.Bits())
 		if err != nil {
-			return err
+			return numError(v, typ, err)
 		}
 		val.SetUint(u)
 	case reflect.Float32, reflect.Float64:
-		f, err := strconv.ParseFloat(v, 64)
+		f, err := strconv.ParseFloat(v, typ.Bits())
 		if err != nil {
-			return err
+			return numError(v, typ, err)
 		}
 		val.SetFloat(f)
 	case reflect.String:
 		val.SetString(v)
 	case reflect.Ptr:
-		return setPtrValue(val, typ, v)
+		return setPtrValue(val, typ, v, opts)
 	case reflect.Slice:
-		return setSliceValue(val, typ, v)
+		return setSliceValue(val, typ, v, opts)
+	case reflect.Map:
+		return setMapValue(val, typ, v, opts)
 	case reflect.Struct:
 		if typ == timeType {
-			t, err := time.Parse(time.RFC3339, v)
+			t, err := parseTime(v, opts.layout)
 			if err != nil {
 				return err
 			}
 			val.Set(reflect.ValueOf(t))
+			return nil
 		}
 		return fmt.Errorf("setFieldValue: %w type [%s]", ErrUnsupported, typ.Kind().String())
 	default:
@@ -325,70 +854,343 @@ func setFieldValue(val reflect.Value, typ reflect.Type, v string) error {
 	return nil
 }
 
-func setPtrValue(val reflect.Value, typ reflect.Type, v string) error {
-	switch typ.Elem().Kind() {
-	case reflect.Bool:
-		b, err := strconv.ParseBool(v)
-		if err != nil {
+// setEnumValue sets val to the allowed value matching v case-insensitively,
+// or equal to v once both are parsed. An integer field of names, see
+// isIndexEnum, is set to the index of the name, or to v itself if it is a
+// valid index.
+func setEnumValue(val reflect.Value, typ reflect.Type, v string, opts valueOptions) error {
+	if typ.Kind() == reflect.Ptr {
+		p := reflect.New(typ.Elem())
+		if err := setEnumValue(p.Elem(), typ.Elem(), v, opts); err != nil {
 			return err
 		}
-		val.Set(reflect.ValueOf(&b))
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
-		i, err := strconv.ParseInt(v, 0, strconv.IntSize)
-		if err != nil {
-			return err
+		val.Set(p)
+		return nil
+	}
+	enum := opts.enum
+	opts.enum = nil
+
+	idx := -1
+	for i, e := range enum {
+		if strings.EqualFold(strings.TrimSpace(v), e) {
+			idx = i
+			break
 		}
-		val.Set(reflect.ValueOf(&i))
-	case reflect.Int64:
-		if typ == durationType {
-			i, err := time.ParseDuration(v)
-			if err != nil {
-				return err
-			}
-			val.Set(reflect.ValueOf(&i))
-		} else {
-			i, err := strconv.ParseInt(v, 0, 64)
-			if err != nil {
-				return err
+	}
+
+	if isIndexEnum(typ, enum, opts) {
+		if idx < 0 {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 || i >= len(enum) {
+				return enumError(v, enum)
 			}
-			val.Set(reflect.ValueOf(&i))
+			idx = i
+		}
+		return setFieldValue(val, typ, strconv.Itoa(idx), opts)
+	}
+	if idx >= 0 {
+		return setFieldValue(val, typ, enum[idx], opts)
+	}
+	p := reflect.New(typ).Elem()
+	if err := setFieldValue(p, typ, v, opts); err != nil {
+		return enumError(v, enum)
+	}
+	for _, e := range enum {
+		if enumMatch(p, e, opts) {
+			val.Set(p)
+			return nil
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
-		u, err := strconv.ParseUint(v, 0, strconv.IntSize)
+	}
+	return enumError(v, enum)
+}
+
+// isIndexEnum reports whether an integer of typ holds the index of its value
+// in enum, that is when typ has no converter or text methods and none of the
+// values of enum is a number, e.g. `oneof=debug|info` rather than `oneof=1|2|4`.
+func isIndexEnum(typ reflect.Type, enum []string, opts valueOptions) bool {
+	_, hasConv := opts.converter(typ)
+	if !isIntKind(typ.Kind()) || typ == durationType || hasConv || isTextType(typ) {
+		return false
+	}
+	for _, e := range enum {
+		if _, err := strconv.ParseInt(e, 0, 64); err == nil {
+			return false
+		}
+		if _, err := strconv.ParseUint(e, 0, 64); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// enumMatch reports whether v is the allowed value e, compared
+// case-insensitively as text, or as values once e is parsed into the type
+// of v, e.g. `0x10` and 16.
+func enumMatch(v reflect.Value, e string, opts valueOptions) bool {
+	if strings.EqualFold(textString(v), e) {
+		return true
+	}
+	p := reflect.New(v.Type()).Elem()
+	if err := setFieldValue(p, v.Type(), e, opts); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(p.Interface(), v.Interface())
+}
+
+func enumError(v string, enum []string) error {
+	return fmt.Errorf("%w [%s], allowed values are [%s]", ErrNotAllowed, v, strings.Join(enum, ", "))
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// setPtrValue sets val to a new value of the type typ points to, converted
+// from v like any other field.
+func setPtrValue(val reflect.Value, typ reflect.Type, v string, opts valueOptions) error {
+	p := reflect.New(typ.Elem())
+	if err := setFieldValue(p.Elem(), typ.Elem(), v, opts); err != nil {
+		return err
+	}
+	val.Set(p)
+	return nil
+}
+
+// numError reports a value out of the range of typ with ErrOutOfRange.
+func numError(v string, typ reflect.Type, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("%w, [%s] overflows type [%s]", ErrOutOfRange, v, typ.String())
+	}
+	return err
+}
+
+// isScalarType reports whether a field of typ is set from a single value by
+// setFieldValue, rather than from a list or from key-value pairs.
+func isScalarType(typ reflect.Type, opts valueOptions) bool {
+	if _, ok := opts.converter(typ); ok || isTextType(typ) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Struct:
+		return typ == timeType
+	case reflect.Ptr:
+		return isScalarType(typ.Elem(), opts)
+	}
+	return false
+}
+
+// setSliceValue replaces the slice with the elements parsed from v. The
+// elements are separated by the `sep` tag (`,` by default) and may be quoted
+// like CSV fields, e.g. `a,"b,c"`. A []byte is decoded with the `encoding`
+// tag, see decodeBytes.
+func setSliceValue(val reflect.Value, typ reflect.Type, v string, opts valueOptions) error {
+	if typ.Elem().Kind() == reflect.Uint8 {
+		b, err := decodeBytes(v, opts)
 		if err != nil {
 			return err
 		}
-		val.Set(reflect.ValueOf(&u))
-	case reflect.Uint64:
-		u, err := strconv.ParseUint(v, 0, 64)
-		if err != nil {
+		val.SetBytes(b)
+		return nil
+	}
+
+	items, err := splitList(v, opts.separator())
+	if err != nil {
+		return err
+	}
+	s := reflect.MakeSlice(typ, 0, len(items))
+	for _, item := range items {
+		e := reflect.New(typ.Elem()).Elem()
+		if err := setFieldValue(e, typ.Elem(), item, opts.elem()); err != nil {
 			return err
 		}
-		val.Set(reflect.ValueOf(&u))
-	case reflect.Float32, reflect.Float64:
-		f, err := strconv.ParseFloat(v, 64)
-		if err != nil {
+		s = reflect.Append(s, e)
+	}
+	val.Set(s)
+	return nil
+}
+
+// splitList splits v by sep, honoring CSV-style double quotes when sep is a
+// single character.
+func splitList(v string, sep string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	comma, size := utf8.DecodeRuneInString(sep)
+	if size != len(sep) || comma == '"' || comma == '\r' || comma == '\n' {
+		items := strings.Split(v, sep)
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items, nil
+	}
+	r := csv.NewReader(strings.NewReader(v))
+	r.Comma = comma
+	r.TrimLeadingSpace = true
+	items, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("splitList: %w", err)
+	}
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items, nil
+}
+
+// setMapValue replaces the map with the entries parsed from v.
+func setMapValue(val reflect.Value, typ reflect.Type, v string, opts valueOptions) error {
+	m := reflect.MakeMap(typ)
+	if err := putMapEntries(m, v, opts); err != nil {
+		return err
+	}
+	val.Set(m)
+	return nil
+}
+
+// putMapEntries parses v as a list of key-value pairs, e.g. `a=1,b=2`, and
+// puts them into the map m.
+func putMapEntries(m reflect.Value, v string, opts valueOptions) error {
+	typ := m.Type()
+	entries, err := splitList(v, opts.separator())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, opts.kvSeparator(), 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("putMapEntries: %w for entry [%s]", ErrEmptyValue, entry)
+		}
+		k := strings.TrimSpace(kv[0])
+		if k == "" {
+			return fmt.Errorf("putMapEntries: %w for entry [%s]", ErrEmptyKey, entry)
+		}
+		key := reflect.New(typ.Key()).Elem()
+		if err := setFieldValue(key, typ.Key(), k, opts.elem()); err != nil {
 			return err
 		}
-		val.Set(reflect.ValueOf(&f))
-	case reflect.String:
-		val.Set(reflect.ValueOf(&v))
+		elem := reflect.New(typ.Elem()).Elem()
+		if err := setFieldValue(elem, typ.Elem(), strings.TrimSpace(kv[1]), opts.elem()); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// deepCopy returns a copy of v sharing no pointer, slice or map with it, but
+// for the values of the leaf types, see isLeafType, and of the structs with
+// unexported fields, such as a tls.Config and its mutex, which are copied as
+// they are, see isOpaqueType.
+func deepCopy(v reflect.Value, conv *converters) reflect.Value {
+	return copyValue(v, conv, make(map[copiedPtr]reflect.Value))
+}
+
+// copiedPtr is a pointer copied by deepCopy, copied once.
+type copiedPtr struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func copyValue(v reflect.Value, conv *converters, seen map[copiedPtr]reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	if isOpaqueType(v.Type(), conv) {
+		c.Set(v)
+		return c
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return c
+		}
+		key := copiedPtr{addr: v.Pointer(), typ: v.Type()}
+		if p, ok := seen[key]; ok {
+			return p
+		}
+		p := reflect.New(v.Type().Elem())
+		seen[key] = p
+		p.Elem().Set(copyValue(v.Elem(), conv, seen))
+		return p
 	case reflect.Struct:
-		if typ == timePtrType {
-			t, err := time.Parse(time.RFC3339, v)
-			if err != nil {
-				return err
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(copyValue(v.Field(i), conv, seen))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		reflect.Copy(c, v)
+		if hasReferences(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				c.Index(i).Set(copyValue(v.Index(i), conv, seen))
 			}
-			val.Set(reflect.ValueOf(&t))
 		}
-		return fmt.Errorf("setPtrValue: %w type [%s]", ErrUnsupported, typ.Kind().String())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i), conv, seen))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(copyValue(iter.Key(), conv, seen), copyValue(iter.Value(), conv, seen))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(copyValue(v.Elem(), conv, seen))
 	default:
-		return fmt.Errorf("setPtrValue: %w type [%s]", ErrUnsupported, typ.Kind().String())
+		c.Set(v)
 	}
-	return nil
+	return c
 }
 
-// TODO
-func setSliceValue(val reflect.Value, typ reflect.Type, v string) error {
-	return nil
+// isOpaqueType reports whether deepCopy copies the values of typ as they
+// are: the leaf types, the structs with unexported fields, and the pointers
+// to the latter. A pointer to a leaf type gets a copy of its value.
+func isOpaqueType(typ reflect.Type, conv *converters) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+		if isLeafType(typ, conv) {
+			return false
+		}
+	} else if isLeafType(typ, conv) {
+		return true
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).PkgPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReferences reports whether the values of typ may share memory when
+// assigned, needing copyValue.
+func hasReferences(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
 }
@@ -1,6 +1,7 @@
 package configurator
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -59,6 +60,22 @@ func TestTags(t *testing.T) {
 	}
 }
 
+func TestTags_Unknown(t *testing.T) {
+	type testStruct struct {
+		Hosts []string `config:"default=a,b"`
+		Typo  string   `config:"env,requried"`
+	}
+	typ := reflect.TypeOf(testStruct{})
+
+	_, err := parseTag(typ.Field(0))
+	assert.True(t, errors.Is(err, ErrInvalidTagFormat))
+	assert.Contains(t, err.Error(), "unknown option [b] of field [Hosts]: a default list needs a separator other than `,`")
+
+	_, err = parseTag(typ.Field(1))
+	assert.True(t, errors.Is(err, ErrInvalidTagFormat))
+	assert.Contains(t, err.Error(), "unknown option [requried] of field [Typo]")
+}
+
 func TestGetStructInfo(t *testing.T) {
 	type Embedded struct {
 		Port int `config:"env=MYSQL_PORT,flag,default=3306"`
@@ -112,3 +129,59 @@ func TestGetStructInfo(t *testing.T) {
 	assert.Equal(t, "", si.Fields()[5].DefVal())
 	assert.True(t, si.Fields()[5].StructField().Type == timePtrType)
 }
+
+func TestGetStructInfo_Elements(t *testing.T) {
+	type tls struct {
+		Cert string `config:"env"`
+	}
+	type listener struct {
+		Port int `config:"env,default=80"`
+		TLS  tls
+	}
+	type upstream struct {
+		Host string `config:"env"`
+	}
+	type node struct {
+		Name     string
+		Children []node
+	}
+	type testStruct struct {
+		Listeners []listener
+		DBs       map[string]*upstream
+		Tree      node
+	}
+
+	cfg := &testStruct{Listeners: []listener{{Port: 1}}}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	listeners := si.Fields()[0]
+	assert.Equal(t, "listeners", listeners.Path())
+	assert.Equal(t, []string{"0"}, listeners.Keys())
+	assert.Len(t, listeners.Elem().Fields(), 2)
+	assert.Equal(t, "listeners[].port", listeners.Elem().Fields()[0].Path())
+	assert.Equal(t, "listeners[].tls.cert", listeners.Elem().Fields()[1].Path())
+	assert.Equal(t, "TLS_CERT", listeners.Elem().Fields()[1].ENVKey())
+
+	err = listeners.Element("1", func(es StructInfo) error {
+		assert.Equal(t, "listeners[1].port", es.Fields()[0].Path())
+		es.Fields()[0].Value().SetInt(2)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []listener{{Port: 1}, {Port: 2}}, cfg.Listeners)
+
+	dbs := si.Fields()[1]
+	err = dbs.Element("primary", func(es StructInfo) error {
+		assert.Equal(t, "dbs[primary].host", es.Fields()[0].Path())
+		es.Fields()[0].Value().SetString("db1")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*upstream{"primary": {Host: "db1"}}, cfg.DBs)
+
+	children := si.Fields()[3]
+	assert.Equal(t, "tree.children", children.Path())
+	assert.NotNil(t, children.Elem())
+	assert.Nil(t, children.Elem().Fields()[1].Elem())
+}
@@ -0,0 +1,166 @@
+package configurator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// secretMask replaces the values of the secrets, that are the fields of the
+// types Secret and SecretBytes or tagged `secret`.
+const secretMask = "******"
+
+// Secret is a string never revealed by fmt, encoding/json or yaml, which
+// print a mask instead. Reveal returns the value. An empty Secret prints
+// empty.
+type Secret string
+
+// Reveal returns the value of the secret.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return maskSecret(len(s))
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) Format(f fmt.State, verb rune) {
+	formatSecret(f, verb, s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// SecretBytes is a []byte never revealed by fmt, encoding/json or yaml, like
+// Secret. It is decoded like any []byte, see the `encoding` tag.
+type SecretBytes []byte
+
+// Reveal returns the value of the secret.
+func (s SecretBytes) Reveal() []byte {
+	return []byte(s)
+}
+
+func (s SecretBytes) String() string {
+	return maskSecret(len(s))
+}
+
+func (s SecretBytes) GoString() string {
+	return s.String()
+}
+
+func (s SecretBytes) Format(f fmt.State, verb rune) {
+	formatSecret(f, verb, s.String())
+}
+
+func (s SecretBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s SecretBytes) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+func maskSecret(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return secretMask
+}
+
+// formatSecret writes the mask s whatever the verb, quoted for %q.
+func formatSecret(f fmt.State, verb rune, s string) {
+	if verb == 'q' {
+		s = strconv.Quote(s)
+	}
+	_, _ = io.WriteString(f, s)
+}
+
+var (
+	secretType      = reflect.TypeOf(Secret(""))
+	secretBytesType = reflect.TypeOf(SecretBytes(nil))
+)
+
+// isSecretType reports whether typ is a Secret or a SecretBytes, or a
+// pointer, a slice, an array or a map of them.
+func isSecretType(typ reflect.Type) bool {
+	for {
+		switch {
+		case typ == secretType || typ == secretBytesType:
+			return true
+		case typ.Kind() == reflect.Ptr, typ.Kind() == reflect.Slice, typ.Kind() == reflect.Array, typ.Kind() == reflect.Map:
+			typ = typ.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// isSecret reports whether the value of f is a secret.
+func (f *fieldInfo) isSecret() bool {
+	return f.tag.secret || isSecretType(f.field.Type)
+}
+
+// isSecretField reports whether the value of the struct field f is a secret.
+func isSecretField(f reflect.StructField) bool {
+	if isSecretType(f.Type) {
+		return true
+	}
+	t, err := parseTag(f)
+	return err == nil && t.secret
+}
+
+// revealString returns the value of a Secret or a SecretBytes.
+func revealString(v reflect.Value) (string, bool) {
+	switch v.Type() {
+	case secretType:
+		return v.String(), true
+	case secretBytesType:
+		return string(v.Bytes()), true
+	}
+	return "", false
+}
+
+// secretError hides the message of an error about a secret, which may hold
+// its value, keeping the errors it wraps for errors.Is and errors.As.
+type secretError struct {
+	err error
+}
+
+// secretSentinels are the errors named by a secretError wrapping them.
+var secretSentinels = []error{
+	ErrOutOfRange, ErrNotAllowed, ErrInvalidLength, ErrPatternMismatch,
+	ErrTypeMismatch, ErrEmptyValue, ErrEmptyKey,
+}
+
+func (e *secretError) Error() string {
+	for _, s := range secretSentinels {
+		if errors.Is(e.err, s) {
+			return s.Error() + ", secret value hidden"
+		}
+	}
+	return "invalid value, secret value hidden"
+}
+
+func (e *secretError) Unwrap() error {
+	return e.err
+}
+
+// hideSecret returns err hiding its message, or nil if err is nil.
+func hideSecret(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &secretError{err: err}
+}
@@ -0,0 +1,129 @@
+package configurator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecret(t *testing.T) {
+	type example struct {
+		Password Secret
+		Key      SecretBytes
+		Empty    Secret
+	}
+	cfg := example{Password: "hunter2", Key: SecretBytes("k3y")}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%d"} {
+		assert.NotContains(t, fmt.Sprintf(format, cfg), "hunter2", format)
+		assert.NotContains(t, fmt.Sprintf(format, cfg), "k3y", format)
+	}
+	assert.Equal(t, "******", cfg.Password.String())
+	assert.Equal(t, `"******"`, fmt.Sprintf("%q", cfg.Key))
+	assert.Equal(t, "", cfg.Empty.String())
+	assert.Equal(t, "hunter2", cfg.Password.Reveal())
+	assert.Equal(t, []byte("k3y"), cfg.Key.Reveal())
+
+	b, err := json.Marshal(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Password":"******","Key":"******","Empty":""}`, string(b))
+	b, err = yaml.Marshal(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "password: '******'\nkey: '******'\nempty: \"\"\n", string(b))
+}
+
+func TestSecret_Load(t *testing.T) {
+	type example struct {
+		Password Secret      `yaml:"password" config:"env"`
+		Token    SecretBytes `yaml:"token" config:"flag,encoding=raw"`
+		PIN      int         `yaml:"pin" config:"env,secret"`
+		Keys     []Secret    `yaml:"keys" config:"default=a|b,sep=|,pattern=^[a-z]$"`
+		Key      SecretBytes `yaml:"key"`
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("password: from-file\nkey: azN5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_PASSWORD", "hunter2")
+	os.Setenv("APP_PIN", "12x4")
+	defer os.Unsetenv("APP_PASSWORD")
+	defer os.Unsetenv("APP_PIN")
+	os.Args = []string{"cmd", "-token=t0ken"}
+	resetForTesting()
+
+	c := NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP"), WithFlagProvider(), WithDefaultProvider())
+	cfg := &example{}
+	err = c.Load(cfg)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	assert.NotContains(t, err.Error(), "12x4")
+	var le *LoadError
+	if assert.True(t, errors.As(err, &le)) {
+		assert.Equal(t, []FieldError{{Path: "pin", Source: SourceEnv, Key: "APP_PIN", RawValue: "******", Err: le.Errors[0].Err}}, le.Errors)
+	}
+	assert.Equal(t, &example{}, cfg)
+
+	os.Setenv("APP_PIN", "1234")
+	assert.NoError(t, c.Load(cfg))
+	assert.Equal(t, 1234, cfg.PIN)
+	assert.Equal(t, "hunter2", cfg.Password.Reveal())
+	assert.Equal(t, "t0ken", string(cfg.Token.Reveal()))
+	assert.Equal(t, "k3y", string(cfg.Key.Reveal()))
+	assert.Equal(t, []Secret{"a", "b"}, cfg.Keys)
+
+	for path, o := range c.Origins() {
+		assert.Equal(t, "******", o.RawValue, path)
+	}
+	assert.NotContains(t, c.Explain("password"), "from-file")
+}
+
+func TestSecret_DecodeError(t *testing.T) {
+	type example struct {
+		PIN int `json:"pin" config:"secret"`
+	}
+
+	f, err := ioutil.TempFile("", "*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"pin": "12x4"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewConfigurator(WithFileProvider(f.Name())).Load(&example{})
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+	assert.False(t, strings.Contains(err.Error(), "12x4"), err.Error())
+	assert.Equal(t, "******", err.(*LoadError).Errors[0].RawValue)
+}
+
+func TestSecret_Validate(t *testing.T) {
+	type example struct {
+		Password Secret `config:"min=8,pattern=^[a-z]+$"`
+	}
+	cfg := &example{Password: "Hunter2"}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = validate(cfg, si, nil)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	assert.NotContains(t, err.Error(), "Hunter2")
+
+	cfg.Password = "hunterhunter"
+	assert.NoError(t, validate(cfg, si, nil))
+}
@@ -0,0 +1,87 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownKey is a key of a file matching no field of the config.
+type unknownKey struct {
+	path         string
+	key          string
+	line, column int
+	known        []string
+}
+
+func (k unknownKey) err() error {
+	if s := suggest(k.key, k.known); s != "" {
+		return fmt.Errorf("%w [%s], did you mean [%s]?", ErrUnknownKey, k.key, s)
+	}
+	return fmt.Errorf("%w [%s]", ErrUnknownKey, k.key)
+}
+
+func newUnknownKey(n fileNode, known []string) unknownKey {
+	return unknownKey{path: n.path, key: n.raw, line: n.line, column: n.column, known: known}
+}
+
+// unknownYAMLKeys appends the keys under n matching no field of typ to keys.
+func unknownYAMLKeys(n *yaml.Node, typ reflect.Type, keys *[]unknownKey) {
+	walkYAML(n, typ, "", &fileVisitor{unknown: func(k fileNode, known []string) {
+		*keys = append(*keys, newUnknownKey(k, known))
+	}})
+}
+
+// unknownJSONKeys returns the keys of data matching no field of typ.
+func unknownJSONKeys(data []byte, typ reflect.Type) ([]unknownKey, error) {
+	var keys []unknownKey
+	err := walkJSON(data, typ, &fileVisitor{unknown: func(k fileNode, known []string) {
+		keys = append(keys, newUnknownKey(k, known))
+	}})
+	return keys, err
+}
+
+// suggest returns the known key closest to key, if close enough to be a typo.
+func suggest(key string, known []string) string {
+	best, bestDist := "", len(key)/3+2
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,131 @@
+package configurator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	layoutUnix      = "unix"
+	layoutUnixMilli = "unixmilli"
+)
+
+// namedLayouts are the layouts the `layout` tag accepts by name, since a tag
+// value cannot hold a comma.
+var namedLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"date":        "2006-01-02",
+	"datetime":    "2006-01-02 15:04:05",
+	"kitchen":     time.Kitchen,
+}
+
+// defaultLayouts are tried in order when a field has no `layout` tag.
+var defaultLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses v with layout, which is a Go layout, one of the names of
+// namedLayouts, `unix` for epoch seconds or `unixmilli` for epoch
+// milliseconds. Without a layout, v may be an RFC 3339 time, a date-time
+// without a zone, a date or epoch seconds; the ones without a zone are UTC.
+func parseTime(v string, layout string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	switch l := strings.ToLower(layout); l {
+	case layoutUnix, layoutUnixMilli:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parseTime: invalid epoch [%s] for layout [%s]", v, layout)
+		}
+		if l == layoutUnixMilli {
+			return time.Unix(0, n*int64(time.Millisecond)), nil
+		}
+		return time.Unix(n, 0), nil
+	case "":
+	default:
+		if named, ok := namedLayouts[l]; ok {
+			layout = named
+		}
+		return time.Parse(layout, v)
+	}
+
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	for _, l := range defaultLayouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parseTime: cannot parse [%s] as RFC 3339 time, date or epoch seconds", v)
+}
+
+// parseDuration parses a duration like time.ParseDuration, accepting the
+// units `d` for days and `w` for weeks too, e.g. `7d12h` or `-1w`.
+func parseDuration(v string) (time.Duration, error) {
+	s := strings.TrimSpace(v)
+	if !strings.ContainsAny(s, "dw") {
+		return time.ParseDuration(s)
+	}
+
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	var extra float64
+	var rest strings.Builder
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] == '.' || '0' <= s[i] && s[i] <= '9') {
+			i++
+		}
+		j := i
+		for j < len(s) && !(s[j] == '.' || '0' <= s[j] && s[j] <= '9') {
+			j++
+		}
+		num, unit := s[:i], s[i:j]
+		if num == "" {
+			return 0, fmt.Errorf("parseDuration: invalid duration [%s]", v)
+		}
+		switch unit {
+		case "d", "w":
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parseDuration: invalid duration [%s]", v)
+			}
+			if unit == "w" {
+				f *= 7
+			}
+			extra += f * float64(24*time.Hour)
+		default:
+			rest.WriteString(s[:j])
+		}
+		s = s[j:]
+	}
+
+	d := time.Duration(0)
+	if rest.Len() > 0 {
+		var err error
+		if d, err = time.ParseDuration(rest.String()); err != nil {
+			return 0, fmt.Errorf("parseDuration: invalid duration [%s]", v)
+		}
+	}
+	if extra+float64(d) > float64(1<<63-1) {
+		return 0, fmt.Errorf("parseDuration: duration [%s] overflows", v)
+	}
+	d += time.Duration(extra)
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,142 @@
+package configurator
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		layout string
+		expect time.Time
+	}{
+		{in: "2020-09-30T22:51:49-08:00", expect: time.Date(2020, 9, 30, 22, 51, 49, 0, time.FixedZone("", -28800))},
+		{in: "2020-09-30T22:51:49.5Z", expect: time.Date(2020, 9, 30, 22, 51, 49, 500000000, time.UTC)},
+		{in: "2020-09-30 22:51:49", expect: time.Date(2020, 9, 30, 22, 51, 49, 0, time.UTC)},
+		{in: "2020-09-30", expect: time.Date(2020, 9, 30, 0, 0, 0, 0, time.UTC)},
+		{in: "1601535109", expect: time.Unix(1601535109, 0)},
+		{in: "1601535109", layout: "unix", expect: time.Unix(1601535109, 0)},
+		{in: "1601535109123", layout: "unixmilli", expect: time.Unix(1601535109, 123000000)},
+		{in: "30/09/2020", layout: "02/01/2006", expect: time.Date(2020, 9, 30, 0, 0, 0, 0, time.UTC)},
+		{in: "2020-09-30", layout: "date", expect: time.Date(2020, 9, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		v, err := parseTime(tt.in, tt.layout)
+		assert.NoError(t, err, tt.in)
+		assert.True(t, tt.expect.Equal(v), "%s: %v != %v", tt.in, tt.expect, v)
+	}
+
+	_, err := parseTime("yesterday", "")
+	assert.Error(t, err)
+	_, err = parseTime("2020-09-30", "unix")
+	assert.Error(t, err)
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect time.Duration
+	}{
+		{in: "1h30m", expect: 90 * time.Minute},
+		{in: "7d12h", expect: 7*24*time.Hour + 12*time.Hour},
+		{in: "1w", expect: 7 * 24 * time.Hour},
+		{in: "1.5d", expect: 36 * time.Hour},
+		{in: "-2d3s", expect: -(48*time.Hour + 3*time.Second)},
+		{in: "1w2d3h4m5s6ms", expect: 9*24*time.Hour + 3*time.Hour + 4*time.Minute + 5*time.Second + 6*time.Millisecond},
+	}
+	for _, tt := range tests {
+		v, err := parseDuration(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.expect, v, tt.in)
+	}
+
+	for _, in := range []string{"d", "1x", "3d1", "999999999w"} {
+		_, err := parseDuration(in)
+		assert.Error(t, err, in)
+	}
+}
+
+type timeExample struct {
+	Start   time.Time       `json:"start" yaml:"start" config:"env,flag,layout=date"`
+	Expire  *time.Time      `json:"expire" yaml:"expire" config:"env,flag,layout=unixmilli"`
+	TTL     time.Duration   `json:"ttl" yaml:"ttl" config:"env,flag,default=1w"`
+	Retries []time.Duration `json:"retries" yaml:"retries" config:"env,flag"`
+}
+
+func TestTimeProviders(t *testing.T) {
+	date := time.Date(2020, 9, 30, 0, 0, 0, 0, time.UTC)
+	milli := time.Unix(1601535109, 123000000)
+	expect := &timeExample{
+		Start:   date,
+		Expire:  &milli,
+		TTL:     7 * 24 * time.Hour,
+		Retries: []time.Duration{time.Second, 2 * 24 * time.Hour},
+	}
+
+	t.Run("env", func(t *testing.T) {
+		envs := map[string]string{
+			"APP_START":   "2020-09-30",
+			"APP_EXPIRE":  "1601535109123",
+			"APP_RETRIES": "1s,2d",
+		}
+		for k, v := range envs {
+			os.Setenv(k, v)
+			defer os.Unsetenv(k)
+		}
+		os.Args = []string{"cmd"}
+		resetForTesting()
+
+		cfg := &timeExample{}
+		err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider()).Load(cfg)
+		assert.NoError(t, err)
+		assertTimeExample(t, expect, cfg)
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		os.Args = []string{"cmd", "-start=2020-09-30", "-expire=1601535109123", "-ttl=7d", "-retries=1s", "-retries=2d"}
+		resetForTesting()
+
+		cfg := &timeExample{}
+		err := NewConfigurator(WithFileProvider(""), WithFlagProvider()).Load(cfg)
+		assert.NoError(t, err)
+		assertTimeExample(t, expect, cfg)
+	})
+
+	files := map[string]string{
+		"*.json": `{"start":"2020-09-30","expire":1601535109123,"ttl":"1w","retries":["1s","2d"]}`,
+		"*.yaml": "start: 2020-09-30\nexpire: 1601535109123\nttl: 1w\nretries: [1s, 2d]\n",
+	}
+	for pattern, content := range files {
+		pattern, content := pattern, content
+		t.Run(pattern, func(t *testing.T) {
+			f, err := ioutil.TempFile("", pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, err = f.WriteString(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &timeExample{}
+			err = NewConfigurator(WithFileProvider(f.Name())).Load(cfg)
+			assert.NoError(t, err)
+			assertTimeExample(t, expect, cfg)
+		})
+	}
+}
+
+func assertTimeExample(t *testing.T, expect, actual *timeExample) {
+	assert.True(t, expect.Start.Equal(actual.Start), "start %v", actual.Start)
+	if assert.NotNil(t, actual.Expire) {
+		assert.True(t, expect.Expire.Equal(*actual.Expire), "expire %v", actual.Expire)
+	}
+	assert.Equal(t, expect.TTL, actual.TTL)
+	assert.Equal(t, expect.Retries, actual.Retries)
+}
@@ -0,0 +1,253 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes parsed from a human-readable size like
+// `512MiB`, `1.5GB` or `1024`. The units KB, MB, GB, TB and PB are powers of
+// 1000, the units KiB, MiB, GiB, TiB and PiB as well as the single letters
+// K, M, G, T and P are powers of 1024. Units are case-insensitive.
+type ByteSize uint64
+
+const (
+	Byte ByteSize = 1
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+	PiB           = 1024 * TiB
+	KB            = 1000 * Byte
+	MB            = 1000 * KB
+	GB            = 1000 * MB
+	TB            = 1000 * GB
+	PB            = 1000 * TB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KiB,
+	"kib": KiB,
+	"kb":  KB,
+	"m":   MiB,
+	"mib": MiB,
+	"mb":  MB,
+	"g":   GiB,
+	"gib": GiB,
+	"gb":  GB,
+	"t":   TiB,
+	"tib": TiB,
+	"tb":  TB,
+	"p":   PiB,
+	"pib": PiB,
+	"pb":  PB,
+}
+
+// ParseByteSize parses a human-readable size.
+func ParseByteSize(s string) (ByteSize, error) {
+	v := strings.TrimSpace(s)
+	i := strings.IndexFunc(v, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(v)
+	}
+	num, unit := v[:i], strings.ToLower(strings.TrimSpace(v[i:]))
+	if num == "" {
+		return 0, fmt.Errorf("ParseByteSize: invalid size [%s]", s)
+	}
+	mul, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("ParseByteSize: unknown unit [%s] in [%s]", unit, s)
+	}
+
+	if !strings.Contains(num, ".") {
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("ParseByteSize: invalid size [%s]: %w", s, err)
+		}
+		if n > math.MaxUint64/uint64(mul) {
+			return 0, fmt.Errorf("ParseByteSize: size [%s] overflows", s)
+		}
+		return ByteSize(n) * mul, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParseByteSize: invalid size [%s]: %w", s, err)
+	}
+	f *= float64(mul)
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("ParseByteSize: size [%s] overflows", s)
+	}
+	return ByteSize(f), nil
+}
+
+// String formats the size with the largest unit dividing it, e.g. `512MiB`.
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		size ByteSize
+		name string
+	}{
+		{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+		{PB, "PB"}, {TB, "TB"}, {GB, "GB"}, {MB, "MB"}, {KB, "KB"},
+	} {
+		if b >= u.size && b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	v, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// UnmarshalJSON accepts a JSON number of bytes as well as a string.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		return json.Unmarshal(data, (*uint64)(b))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// URL is a URL parsed with url.Parse.
+type URL struct {
+	url.URL
+}
+
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *URL) UnmarshalText(text []byte) error {
+	v, err := url.Parse(string(text))
+	if err != nil {
+		return err
+	}
+	u.URL = *v
+	return nil
+}
+
+// IPNet is an IP network parsed from a CIDR like `10.0.0.0/8`. A single IP
+// address is parsed as a network of that address only.
+type IPNet struct {
+	net.IPNet
+}
+
+func (n IPNet) String() string {
+	if n.IP == nil {
+		return ""
+	}
+	return n.IPNet.String()
+}
+
+func (n IPNet) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+func (n *IPNet) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("IPNet: invalid IP address [%s]", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		n.IPNet = net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return nil
+	}
+	_, v, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	n.IPNet = *v
+	return nil
+}
+
+// Regexp is a regular expression compiled with regexp.Compile.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r Regexp) String() string {
+	if r.Regexp == nil {
+		return ""
+	}
+	return r.Regexp.String()
+}
+
+func (r Regexp) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Regexp) UnmarshalText(text []byte) error {
+	v, err := regexp.Compile(string(text))
+	if err != nil {
+		return err
+	}
+	r.Regexp = v
+	return nil
+}
+
+// FileMode is a file mode parsed from an octal number like `0644` or `644`.
+type FileMode os.FileMode
+
+func (m FileMode) String() string {
+	return fmt.Sprintf("%04o", uint32(m))
+}
+
+func (m FileMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *FileMode) UnmarshalText(text []byte) error {
+	s := strings.TrimPrefix(strings.TrimSpace(string(text)), "0o")
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return fmt.Errorf("FileMode: invalid mode [%s]: %w", text, err)
+	}
+	*m = FileMode(v)
+	return nil
+}
+
+// UnmarshalJSON accepts a JSON number as well as a string, both read as
+// octal like the other sources do, so that `644` is 0644.
+func (m *FileMode) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		return m.UnmarshalText([]byte(n))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return m.UnmarshalText([]byte(s))
+}
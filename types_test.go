@@ -0,0 +1,104 @@
+package configurator
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect ByteSize
+		str    string
+	}{
+		{in: "1024", expect: KiB, str: "1KiB"},
+		{in: "512MiB", expect: 512 * MiB, str: "512MiB"},
+		{in: "512m", expect: 512 * MiB, str: "512MiB"},
+		{in: "1.5GB", expect: 1500 * MB, str: "1500MB"},
+		{in: "2 kb", expect: 2 * KB, str: "2KB"},
+		{in: "1500", expect: 1500, str: "1500B"},
+	}
+	for _, tt := range tests {
+		b, err := ParseByteSize(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.expect, b, tt.in)
+		assert.Equal(t, tt.str, b.String(), tt.in)
+	}
+
+	for _, in := range []string{"", "MiB", "12XB", "20000000PiB"} {
+		_, err := ParseByteSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+type typesExample struct {
+	Size  ByteSize `json:"size" yaml:"size" config:"env,flag,default=1KiB"`
+	URL   URL      `json:"url" yaml:"url" config:"env,flag"`
+	Net   IPNet    `json:"net" yaml:"net" config:"env,flag"`
+	Re    Regexp   `json:"re" yaml:"re" config:"env,flag"`
+	Mode  FileMode `json:"mode" yaml:"mode" config:"env,flag,default=0600"`
+	Hosts []IPNet  `json:"hosts" yaml:"hosts" config:"env"`
+}
+
+func TestTypes_Providers(t *testing.T) {
+	envs := map[string]string{
+		"APP_URL":   "https://example.com/a?b=c",
+		"APP_NET":   "10.0.0.0/8",
+		"APP_HOSTS": "127.0.0.1,::1",
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	os.Args = []string{"cmd", "-re=^a+$", "-size=512MiB"}
+	resetForTesting()
+
+	cfg := &typesExample{}
+	err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithFlagProvider(), WithDefaultProvider()).Load(cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 512*MiB, cfg.Size)
+	assert.Equal(t, "https://example.com/a?b=c", cfg.URL.String())
+	assert.Equal(t, "10.0.0.0/8", cfg.Net.String())
+	assert.True(t, cfg.Re.MatchString("aa"))
+	assert.Equal(t, FileMode(0600), cfg.Mode)
+	assert.Equal(t, "127.0.0.1/32", cfg.Hosts[0].String())
+	assert.Equal(t, "::1/128", cfg.Hosts[1].String())
+}
+
+func TestTypes_Files(t *testing.T) {
+	jsonIn := `{"size":"1.5GB","url":"http://x/y","net":"192.168.0.0/16","re":"b+","mode":"0755","hosts":["::1"]}`
+	var j typesExample
+	assert.NoError(t, json.Unmarshal([]byte(jsonIn), &j))
+	assert.Equal(t, 1500*MB, j.Size)
+	assert.Equal(t, FileMode(0755), j.Mode)
+
+	var n typesExample
+	assert.NoError(t, json.Unmarshal([]byte(`{"size":2048,"mode":644}`), &n))
+	assert.Equal(t, 2*KiB, n.Size)
+	assert.Equal(t, FileMode(0644), n.Mode)
+	assert.Error(t, json.Unmarshal([]byte(`{"mode":648}`), &n))
+	assert.Error(t, json.Unmarshal([]byte(`{"mode":6.5}`), &n))
+
+	out, err := json.Marshal(j)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"size":"1500MB","url":"http://x/y","net":"192.168.0.0/16","re":"b+","mode":"0755","hosts":["::1/128"]}`, string(out))
+
+	yamlIn := "size: 64KiB\nurl: http://x/y\nnet: 10.1.0.0/16\nre: c+\nmode: 0644\n"
+	var y typesExample
+	assert.NoError(t, yaml.Unmarshal([]byte(yamlIn), &y))
+	assert.Equal(t, 64*KiB, y.Size)
+	assert.Equal(t, "http://x/y", y.URL.String())
+	assert.Equal(t, "10.1.0.0/16", y.Net.String())
+	assert.Equal(t, "c+", y.Re.String())
+	assert.Equal(t, FileMode(0644), y.Mode)
+
+	b, err := yaml.Marshal(y)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "size: 64KiB")
+	assert.Contains(t, string(b), `mode: "0644"`)
+}
@@ -0,0 +1,424 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator is implemented by a config, or any struct within, checking the
+// rules spanning several fields. Validate is called once all the providers
+// have run.
+type Validator interface {
+	Validate() error
+}
+
+// Violation is a field breaking one of its validation tags, or a struct whose
+// Validate failed. The path of the root struct is empty.
+type Violation struct {
+	Path string
+	Err  error
+}
+
+// ValidationError holds every violation found once all the providers have
+// run.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		if v.Path == "" {
+			msgs[i] = v.Err.Error()
+			continue
+		}
+		msgs[i] = fmt.Sprintf("[%s] %v", v.Path, v.Err)
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+// Is reports whether target is ErrValidation or matches the error of any of
+// the violations.
+func (e *ValidationError) Is(target error) bool {
+	if target == ErrValidation {
+		return true
+	}
+	for _, v := range e.Violations {
+		if errors.Is(v.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the `required`, `min`, `max`, `len`, `oneof` and `pattern`
+// tags of the fields, including the ones of the elements of collections, then
+// calls the Validators of the struct v points to.
+func validate(v interface{}, si StructInfo, conv *converters) error {
+	var violations []Violation
+	if err := validateFields(si.Fields(), &violations); err != nil {
+		return err
+	}
+	callValidators(reflect.ValueOf(v).Elem(), "", conv, map[structRef]bool{}, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+func validateFields(fields []FieldInfo, violations *[]Violation) error {
+	for _, fi := range fields {
+		f, ok := fi.(*fieldInfo)
+		if !ok {
+			continue
+		}
+		if err := f.validate(); err != nil {
+			if f.isSecret() {
+				err = hideSecret(err)
+			}
+			*violations = append(*violations, Violation{Path: f.Path(), Err: err})
+			continue
+		}
+		if f.elem == nil {
+			continue
+		}
+		for _, key := range f.Keys() {
+			if nilElement(f.val, key) {
+				continue
+			}
+			err := f.Element(key, func(si StructInfo) error {
+				return validateFields(si.Fields(), violations)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nilElement reports whether the element at key of a slice or map of struct
+// pointers is nil, which Element would allocate.
+func nilElement(v reflect.Value, key string) bool {
+	if v.Type().Elem().Kind() != reflect.Ptr {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if i, err := strconv.Atoi(key); err == nil && i < v.Len() {
+			return v.Index(i).IsNil()
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if fmt.Sprint(k.Interface()) == key {
+				return v.MapIndex(k).IsNil()
+			}
+		}
+	}
+	return false
+}
+
+// callValidators calls Validate on v and on every struct within, through
+// pointers and collections, following the paths of FieldInfo.Path. An
+// embedded struct is validated on its own only if v does not promote its
+// Validate.
+func callValidators(v reflect.Value, path string, conv *converters, seen map[structRef]bool, violations *[]Violation) {
+	ref := structRef{addr: v.UnsafeAddr(), typ: v.Type()}
+	if seen[ref] {
+		return
+	}
+	seen[ref] = true
+
+	validator, isValidator := v.Addr().Interface().(Validator)
+	if isValidator {
+		if err := validator.Validate(); err != nil {
+			*violations = append(*violations, Violation{Path: path, Err: err})
+		}
+	}
+
+	typ := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		ft := typ.Field(i)
+		fv := v.Field(i)
+		if !fv.CanSet() || isLeafType(ft.Type, conv) {
+			continue
+		}
+		name := path
+		if !ft.Anonymous {
+			name = joinPath(path, strings.ToLower(ft.Name))
+		} else if isValidator {
+			continue
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		switch {
+		case fv.Kind() == reflect.Struct:
+			callValidators(fv, name, conv, seen, violations)
+		case structElemType(ft.Type, conv) == nil:
+		case fv.Kind() == reflect.Slice:
+			for j := 0; j < fv.Len(); j++ {
+				if e := derefStruct(fv.Index(j)); e.IsValid() {
+					callValidators(e, fmt.Sprintf("%s[%d]", name, j), conv, seen, violations)
+				}
+			}
+		case fv.Kind() == reflect.Map:
+			keys := fv.MapKeys()
+			sort.Slice(keys, func(a, b int) bool {
+				return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
+			})
+			for _, k := range keys {
+				// map elements are not addressable
+				e := reflect.New(fv.Type().Elem()).Elem()
+				e.Set(fv.MapIndex(k))
+				if e = derefStruct(e); e.IsValid() {
+					callValidators(e, fmt.Sprintf("%s[%v]", name, k.Interface()), conv, seen, violations)
+				}
+			}
+		}
+	}
+}
+
+// structRef identifies a struct visited by callValidators, so that pointer
+// cycles are followed only once. An embedded struct may share the address of
+// its parent, hence the type.
+type structRef struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// derefStruct returns the struct v is or points to, or an invalid value for a
+// nil pointer.
+func derefStruct(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// missing reports whether no provider set the field, a value holding it, or
+// for a collection one of its elements, in the Load validated, whatever its
+// value: a zero value set on purpose is kept, and a value left in the target
+// is not a value loaded. Without the origins of a Load, a zero field is
+// missing.
+func (f *fieldInfo) missing() bool {
+	if f.origins == nil {
+		return f.val.IsZero()
+	}
+	if f.origins.hasElements(f.Path()) {
+		return false
+	}
+	for _, p := range f.lineage() {
+		if f.origins.has(p.Path()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fieldInfo) validate() error {
+	t := f.tag
+	v := f.val
+	if t.required && f.missing() {
+		return ErrRequired
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	opts := f.options()
+	opts.enum = nil
+	if t.min != "" {
+		if err := checkBound(v, t.min, opts, true); err != nil {
+			return err
+		}
+	}
+	if t.max != "" {
+		if err := checkBound(v, t.max, opts, false); err != nil {
+			return err
+		}
+	}
+	if t.hasLen {
+		if n, ok := valueLen(v); ok && n != t.length {
+			return fmt.Errorf("%w, got %d, want %d", ErrInvalidLength, n, t.length)
+		}
+	}
+	if len(t.enum) > 0 {
+		err := eachValue(v, func(e reflect.Value) error {
+			return checkEnum(e, t.enum, opts)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if t.pattern != nil {
+		return eachValue(v, func(e reflect.Value) error {
+			s := textString(e)
+			if r, ok := revealString(e); ok {
+				s = r
+			}
+			if !t.pattern.MatchString(s) {
+				return fmt.Errorf("%w [%s], pattern is [%s]", ErrPatternMismatch, s, t.pattern)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// valueLen returns the length of a string, in runes, or of a slice, an array
+// or a map.
+func valueLen(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+// checkBound checks a number or a time against bound, converted to the type
+// of v by setFieldValue, or the length of anything else, see valueLen,
+// against bound as an integer.
+func checkBound(v reflect.Value, bound string, opts valueOptions, isMin bool) error {
+	name := "max"
+	if isMin {
+		name = "min"
+	}
+
+	if n, ok := valueLen(v); ok && !isTextType(v.Type()) {
+		b, err := strconv.Atoi(bound)
+		if err != nil {
+			return fmt.Errorf("%w, `%s=%s` requires an integer length", ErrInvalidTagFormat, name, bound)
+		}
+		if isMin && n < b || !isMin && n > b {
+			return fmt.Errorf("%w, length %d is out of %s [%d]", ErrOutOfRange, n, name, b)
+		}
+		return nil
+	}
+
+	b := reflect.New(v.Type()).Elem()
+	if err := setFieldValue(b, v.Type(), bound, opts); err != nil {
+		return fmt.Errorf("%w, `%s=%s`: %v", ErrInvalidTagFormat, name, bound, err)
+	}
+	c, ok := compareValues(v, b)
+	if !ok {
+		return fmt.Errorf("%w, `%s` for type [%s]", ErrUnsupported, name, v.Type().String())
+	}
+	if isMin && c < 0 || !isMin && c > 0 {
+		return fmt.Errorf("%w [%s], %s is [%s]", ErrOutOfRange, textString(v), name, bound)
+	}
+	return nil
+}
+
+// compareValues compares two numbers or times of the same type.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Type() == timeType {
+		x, y := a.Interface().(time.Time), b.Interface().(time.Time)
+		return sign(x.Before(y), x.After(y)), true
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(a.Int() < b.Int(), a.Int() > b.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(a.Uint() < b.Uint(), a.Uint() > b.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return sign(a.Float() < b.Float(), a.Float() > b.Float()), true
+	}
+	return 0, false
+}
+
+func sign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// eachValue calls fn with v, or with every element of v if it is a slice, an
+// array or a map other than a []byte, skipping nil pointers.
+func eachValue(v reflect.Value, fn func(reflect.Value) error) error {
+	var values []reflect.Value
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8, isTextType(v.Type()):
+		values = []reflect.Value{v}
+	case v.Kind() == reflect.Slice, v.Kind() == reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	case v.Kind() == reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			values = append(values, iter.Value())
+		}
+	default:
+		values = []reflect.Value{v}
+	}
+
+	for _, e := range values {
+		for e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				break
+			}
+			e = e.Elem()
+		}
+		if e.Kind() == reflect.Ptr {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEnum checks v against the values of the `oneof` tag, the same way
+// setEnumValue converts them: an integer of names holds the index of its
+// value, see isIndexEnum.
+func checkEnum(v reflect.Value, enum []string, opts valueOptions) error {
+	typ := v.Type()
+	if isIndexEnum(typ, enum, opts) {
+		var idx uint64
+		switch typ.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			idx = v.Uint()
+		default:
+			// a negative index wraps around past the values
+			idx = uint64(v.Int())
+		}
+		if idx >= uint64(len(enum)) {
+			return enumError(fmt.Sprint(v.Interface()), enum)
+		}
+		return nil
+	}
+	for _, e := range enum {
+		if enumMatch(v, e, opts) {
+			return nil
+		}
+	}
+	return enumError(textString(v), enum)
+}
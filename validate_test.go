@@ -0,0 +1,231 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	type listener struct {
+		Host string `config:"required"`
+		Port int    `config:"min=1,max=65535"`
+	}
+	type example struct {
+		Name      string        `config:"required,pattern=^[a-z][a-z0-9-]*$"`
+		Level     string        `config:"oneof=debug|info|warn"`
+		Mode      int           `config:"oneof=fast|slow"`
+		Workers   *int          `config:"min=1"`
+		Timeout   time.Duration `config:"min=1s,max=1m"`
+		Size      ByteSize      `config:"max=1MiB"`
+		Tags      []string      `config:"min=1,max=3,pattern=^[a-z]+$"`
+		Code      string        `config:"len=4"`
+		Since     time.Time     `config:"min=2020-01-01"`
+		Listeners []listener
+	}
+
+	workers := 0
+	cfg := &example{
+		Name:      "svc-1",
+		Level:     "INFO",
+		Mode:      1,
+		Workers:   &workers,
+		Timeout:   30 * time.Second,
+		Size:      512 * KiB,
+		Tags:      []string{"a", "b"},
+		Code:      "abcd",
+		Since:     time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Listeners: []listener{{Host: "a", Port: 80}},
+	}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = validate(cfg, si, nil)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	assert.Equal(t, []Violation{{Path: "workers", Err: err.(*ValidationError).Violations[0].Err}}, err.(*ValidationError).Violations)
+
+	workers = 4
+	assert.NoError(t, validate(cfg, si, nil))
+
+	cfg.Name = "1svc"
+	cfg.Level = "trace"
+	cfg.Mode = 2
+	cfg.Timeout = time.Hour
+	cfg.Size = 2 * MiB
+	cfg.Tags = []string{"a", "B"}
+	cfg.Code = "abc"
+	cfg.Since = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg.Listeners = append(cfg.Listeners, listener{Port: 70000})
+	err = validate(cfg, si, nil)
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.True(t, errors.Is(err, ErrPatternMismatch))
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.True(t, errors.Is(err, ErrInvalidLength))
+	assert.True(t, errors.Is(err, ErrRequired))
+
+	var paths []string
+	for _, v := range err.(*ValidationError).Violations {
+		paths = append(paths, v.Path)
+	}
+	assert.Equal(t, []string{
+		"name", "level", "mode", "timeout", "size", "tags", "code", "since",
+		"listeners[1].host", "listeners[1].port",
+	}, paths)
+	assert.Contains(t, err.Error(), "[listeners[1].host] required value missing")
+}
+
+func TestValidate_Load(t *testing.T) {
+	type example struct {
+		Host string `config:"env,required"`
+		Port int    `config:"env,default=80,min=1"`
+	}
+
+	err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider()).Load(&example{})
+	assert.True(t, errors.Is(err, ErrRequired))
+
+	os.Setenv("APP_HOST", "localhost")
+	defer os.Unsetenv("APP_HOST")
+	cfg := &example{}
+	err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP"), WithDefaultProvider()).Load(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{Host: "localhost", Port: 80}, cfg)
+}
+
+func TestValidate_RequiredSet(t *testing.T) {
+	type listener struct {
+		Port int `yaml:"port" config:"required"`
+	}
+	type example struct {
+		Retries   int        `config:"env,required"`
+		Listeners []listener `yaml:"listeners"`
+	}
+
+	f, err := ioutil.TempFile("", "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("listeners:\n  - port: 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_RETRIES", "0")
+	defer os.Unsetenv("APP_RETRIES")
+	cfg := &example{}
+	err = NewConfigurator(WithFileProvider(f.Name()), WithENVProvider("APP")).Load(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{Listeners: []listener{{}}}, cfg)
+
+	os.Unsetenv("APP_RETRIES")
+	cfg = &example{Retries: 3}
+	err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(cfg)
+	assert.True(t, errors.Is(err, ErrRequired))
+	assert.Contains(t, err.Error(), "[retries] required value missing")
+}
+
+func TestValidate_RequiredElements(t *testing.T) {
+	type backend struct {
+		Host string `config:"env"`
+	}
+	type example struct {
+		Backends []backend          `config:"env,required"`
+		Pools    map[string]backend `config:"env,required"`
+	}
+
+	os.Setenv("APP_BACKENDS_0_HOST", "a")
+	os.Setenv("APP_POOLS_MAIN_HOST", "b")
+	defer os.Unsetenv("APP_BACKENDS_0_HOST")
+	defer os.Unsetenv("APP_POOLS_MAIN_HOST")
+	cfg := &example{}
+	err := NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &example{Backends: []backend{{Host: "a"}}, Pools: map[string]backend{"main": {Host: "b"}}}, cfg)
+
+	os.Unsetenv("APP_POOLS_MAIN_HOST")
+	err = NewConfigurator(WithFileProvider(""), WithENVProvider("APP")).Load(&example{})
+	assert.True(t, errors.Is(err, ErrRequired))
+	assert.Contains(t, err.Error(), "[pools] required value missing")
+}
+
+func TestParseTag_Validation(t *testing.T) {
+	type example struct {
+		Name string `config:"pattern=["`
+	}
+	_, err := getStructInfo(&example{}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidTagFormat))
+}
+
+type testTLS struct {
+	Cert string
+	Key  string
+}
+
+func (t testTLS) Validate() error {
+	if (t.Cert == "") != (t.Key == "") {
+		return errors.New("cert and key must be set together")
+	}
+	return nil
+}
+
+type testPool struct {
+	MinConns int
+	MaxConns int
+}
+
+func (p *testPool) Validate() error {
+	if p.MaxConns < p.MinConns {
+		return fmt.Errorf("%w, max conns %d < min conns %d", ErrOutOfRange, p.MaxConns, p.MinConns)
+	}
+	return nil
+}
+
+type testServer struct {
+	testPool
+	TLS   *testTLS
+	Pools map[string]testPool
+	Peers []*testServer
+	Name  string `config:"required"`
+}
+
+func (s *testServer) Validate() error {
+	if s.Name == "admin" {
+		return errors.New("reserved name")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	cfg := &testServer{
+		Name:     "admin",
+		testPool: testPool{MinConns: 2, MaxConns: 1},
+		TLS:      &testTLS{Cert: "cert.pem"},
+		Pools:    map[string]testPool{"b": {MaxConns: 1}, "a": {MinConns: 1}},
+		Peers:    []*testServer{{Name: "x"}, nil, {TLS: &testTLS{Key: "k"}}},
+	}
+	si, err := getStructInfo(cfg, nil)
+	assert.NoError(t, err)
+
+	err = validate(cfg, si, nil)
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+
+	var paths []string
+	for _, v := range err.(*ValidationError).Violations {
+		paths = append(paths, v.Path)
+	}
+	assert.Equal(t, []string{"peers[2].name", "", "tls", "pools[a]", "peers[2].tls"}, paths)
+	assert.Contains(t, err.Error(), "; reserved name; [tls] cert and key must be set together")
+	assert.Nil(t, cfg.Peers[1])
+
+	cfg.Name = "main"
+	cfg.TLS.Key = "key.pem"
+	cfg.Pools["a"] = testPool{MinConns: 1, MaxConns: 1}
+	cfg.Peers = cfg.Peers[:1]
+	assert.NoError(t, validate(cfg, si, nil))
+}
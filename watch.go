@@ -0,0 +1,137 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Watch reloads the config whenever the file of the file provider changes,
+// until ctx is done, checking the file every watch interval, see
+// WithWatchInterval. A change is reloaded once the file stays the same for an
+// interval, so that a file being written, or replaced by a rename like the
+// editors do, is read whole.
+//
+// Every reload runs all the providers on a new value of the type target
+// points to, and passes it to onChange only if it loads and validates; the
+// errors of the other reloads go to the handler set by
+// WithWatchErrorHandler. target itself is left alone.
+func (c *Configurator) Watch(ctx context.Context, target interface{}, onChange func(interface{})) error {
+	typ, err := c.configType(target)
+	if err != nil {
+		return err
+	}
+	return c.watchFile(ctx, func() error {
+		v, old, err := c.reload(typ)
+		if err != nil {
+			return err
+		}
+		onChange(v)
+		c.notify(old, v)
+		return nil
+	})
+}
+
+// watchFile calls reload whenever the file of the file provider changes,
+// until ctx is done, reporting its errors to the handler set by
+// WithWatchErrorHandler.
+func (c *Configurator) watchFile(ctx context.Context, reload func() error) error {
+	var filename string
+	for _, p := range c.providers {
+		if fp, ok := p.(*fileProvider); ok {
+			filename = fp.filename
+		}
+	}
+	if filename == "" {
+		return fmt.Errorf("Configurator/Watch: %w without a file provider", ErrUnsupported)
+	}
+
+	interval := c.watchInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	last := statFile(filename)
+	seen := last
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		cur := statFile(filename)
+		if !sameFile(cur, seen) {
+			// changing, or replaced and not there yet
+			seen = cur
+			continue
+		}
+		if cur == nil || sameFile(cur, last) {
+			continue
+		}
+		last = cur
+
+		if err := reload(); err != nil && c.watchErrors != nil {
+			c.watchErrors(err)
+		}
+	}
+}
+
+// configType returns the struct type target points to. Its fields are checked
+// on a new value of the type, target itself being left alone.
+func (c *Configurator) configType(target interface{}) (reflect.Type, error) {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+	if _, err := walkStruct(reflect.New(typ.Elem()).Interface(), nil, c.conv, nil); err != nil {
+		return nil, err
+	}
+	return typ.Elem(), nil
+}
+
+// reload loads a new config of type typ, recording it as the last config
+// loaded only if it loads and validates. It returns the config loaded
+// before, if any, for notify.
+func (c *Configurator) reload(typ reflect.Type) (v interface{}, old interface{}, err error) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	v = reflect.New(typ).Interface()
+	o := newOrigins()
+	si, err := walkStruct(v, nil, c.conv, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.provide(v, si); err != nil {
+		return nil, nil, err
+	}
+	c.mu.Lock()
+	old = c.loaded
+	c.origins, c.loaded = o, v
+	c.mu.Unlock()
+	if old != nil && reflect.TypeOf(old) != reflect.TypeOf(v) {
+		old = nil
+	}
+	return v, old, nil
+}
+
+// statFile returns the file info of name, or nil if it cannot be read.
+func statFile(name string) os.FileInfo {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil
+	}
+	return fi
+}
+
+// sameFile reports whether a and b are the same unchanged file, a new file
+// renamed over the path being another file.
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
@@ -0,0 +1,97 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	type example struct {
+		Level string `yaml:"level" config:"oneof=debug|info|warn"`
+		Port  int    `yaml:"port" config:"flag"`
+	}
+
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		// like an editor, write a new file and rename it over the old one
+		tmp := filepath.Join(dir, "config.yaml~")
+		if err := ioutil.WriteFile(tmp, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("level: info\n")
+
+	os.Args = []string{"cmd", "-port=8080"}
+	resetForTesting()
+
+	errs := make(chan error, 10)
+	c := NewConfigurator(WithFileProvider(name), WithFlagProvider(), WithWatchInterval(10*time.Millisecond),
+		WithWatchErrorHandler(func(err error) { errs <- err }))
+	cfg := &example{}
+	assert.NoError(t, c.Load(cfg))
+
+	changes := make(chan *example, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(ctx, cfg, func(v interface{}) {
+			changes <- v.(*example)
+		})
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	write("level: debug\n")
+	select {
+	case v := <-changes:
+		assert.Equal(t, &example{Level: "debug", Port: 8080}, v)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change delivered")
+	}
+	assert.Equal(t, &example{Level: "info", Port: 8080}, cfg)
+	assert.Equal(t, "level: file "+name+`:1:8="debug"`, c.Explain("level"))
+
+	write("level: trace\n")
+	select {
+	case err := <-errs:
+		assert.True(t, errors.Is(err, ErrNotAllowed))
+	case <-time.After(2 * time.Second):
+		t.Fatal("no error reported")
+	}
+	assert.Empty(t, changes)
+	assert.Equal(t, "level: file "+name+`:1:8="debug"`, c.Explain("level"))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestWatch_NoFile(t *testing.T) {
+	type pool struct {
+		Size int
+	}
+	type example struct {
+		Port int
+		Pool *pool
+	}
+	target := &example{}
+	err := NewConfigurator(WithFileProvider("")).Watch(context.Background(), target, func(interface{}) {})
+	assert.True(t, errors.Is(err, ErrUnsupported))
+	assert.Nil(t, target.Pool, "the target is left alone")
+
+	err = NewConfigurator().Watch(context.Background(), example{}, func(interface{}) {})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}